@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+)
+
+// Clone backends selectable with -backend
+const (
+	backendExec  = "exec"   // Shells out to the git binary
+	backendGoGit = "go-git" // Native Go implementation, no git binary needed
+)
+
+// CloneBackend clones and updates repositories
+type CloneBackend interface {
+	// Clone clones cloneURL into repoPath using the configured clone options
+	Clone(ctx context.Context, config Config, rec *RepoRecord, cloneURL, repoPath string) error
+	// UpdateMirror fetches all refs of an existing mirror, pruning refs deleted on the remote
+	UpdateMirror(ctx context.Context, config Config, rec *RepoRecord, repoPath string) error
+	// UpdateShallow fetches an existing shallow clone, refetching it to -depth or unshallowing it with
+	// -unshallow (otherwise the shallow boundary is kept)
+	// and fast-forwards the checked-out branch to its upstream
+	UpdateShallow(ctx context.Context, config Config, rec *RepoRecord, repoPath string) error
+}
+
+// newCloneBackend returns the clone backend selected with -backend
+func newCloneBackend(config Config) CloneBackend {
+	if config.Backend == backendGoGit {
+		return goGitBackend{fallback: execBackend{}}
+	}
+	return execBackend{}
+}
+
+// execBackend runs the git binary, using the user's git configuration and credential helpers
+type execBackend struct{}
+
+func (execBackend) Clone(ctx context.Context, config Config, rec *RepoRecord, cloneURL, repoPath string) error {
+	if err := runGit(ctx, "", lfsEnv(config), cloneArgs(config, cloneURL, repoPath)...); err != nil {
+		return fmt.Errorf("git clone failed: %w", err)
+	}
+	return nil
+}
+
+func (execBackend) UpdateMirror(ctx context.Context, config Config, rec *RepoRecord, repoPath string) error {
+	if err := runGit(ctx, repoPath, nil, "remote", "update", "--prune"); err != nil {
+		return fmt.Errorf("git remote update failed: %w", err)
+	}
+	return nil
+}
+
+func (execBackend) UpdateShallow(ctx context.Context, config Config, rec *RepoRecord, repoPath string) error {
+	args := []string{"fetch", "--progress", "origin"}
+	switch {
+	case config.Depth > 0:
+		args = []string{"fetch", "--progress", fmt.Sprintf("--depth=%d", config.Depth), "origin"}
+	case config.Unshallow:
+		args = []string{"fetch", "--progress", "--unshallow", "origin"}
+	}
+	if err := runGit(ctx, repoPath, nil, args...); err != nil {
+		return fmt.Errorf("git fetch failed: %w", err)
+	}
+
+	// Detached or locally created branches have no upstream to fast-forward to
+	if _, err := gitOutputContext(ctx, repoPath, nil, "rev-parse", "--abbrev-ref", "@{upstream}"); err != nil {
+		return nil
+	}
+	if _, err := gitOutputContext(ctx, repoPath, lfsEnv(config), "merge", "--ff-only", "@{upstream}"); err != nil {
+		return fmt.Errorf("fast-forward failed: %w", err)
+	}
+	return nil
+}
+
+// goGitBackend clones with go-git so the tool runs where no git binary is installed. Features
+// go-git lacks (partial clones, unshallowing) are delegated to the fallback backend.
+type goGitBackend struct {
+	fallback CloneBackend
+}
+
+func (b goGitBackend) Clone(ctx context.Context, config Config, rec *RepoRecord, cloneURL, repoPath string) error {
+	if config.Filter != "" {
+		fmt.Printf("  go-git does not support partial clones, using git\n")
+		return b.fallback.Clone(ctx, config, rec, cloneURL, repoPath)
+	}
+
+	auth, err := goGitAuth(config, rec)
+	if err != nil {
+		return err
+	}
+	_, err = git.PlainCloneContext(ctx, repoPath, config.Mirror, &git.CloneOptions{
+		URL:          cloneURL,
+		Auth:         auth,
+		Mirror:       config.Mirror,
+		Depth:        config.Depth,
+		SingleBranch: config.SingleBranch,
+		Progress:     gitProgress(ctx, os.Stdout),
+	})
+	if err != nil {
+		return fmt.Errorf("go-git clone failed: %w", err)
+	}
+	return nil
+}
+
+func (b goGitBackend) UpdateMirror(ctx context.Context, config Config, rec *RepoRecord, repoPath string) error {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open mirror: %w", err)
+	}
+	auth, err := goGitAuth(config, rec)
+	if err != nil {
+		return err
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []gitconfig.RefSpec{"+refs/*:refs/*"},
+		Auth:     auth,
+		Force:    true,
+		Prune:    true,
+		Progress: gitProgress(ctx, os.Stdout),
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("go-git fetch failed: %w", err)
+	}
+	return nil
+}
+
+func (b goGitBackend) UpdateShallow(ctx context.Context, config Config, rec *RepoRecord, repoPath string) error {
+	if config.Depth == 0 {
+		fmt.Printf("  go-git can only update shallow clones to -depth, using git\n")
+		return b.fallback.UpdateShallow(ctx, config, rec, repoPath)
+	}
+
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+	auth, err := goGitAuth(config, rec)
+	if err != nil {
+		return err
+	}
+
+	// Pull only ever fast-forwards the checked-out branch
+	err = worktree.PullContext(ctx, &git.PullOptions{
+		RemoteName: "origin",
+		Auth:       auth,
+		Depth:      config.Depth,
+		Progress:   gitProgress(ctx, os.Stdout),
+	})
+	switch {
+	case err == nil, errors.Is(err, git.NoErrAlreadyUpToDate):
+		return nil
+	case errors.Is(err, git.ErrNonFastForwardUpdate):
+		return fmt.Errorf("fast-forward failed: %w", err)
+	}
+	return fmt.Errorf("go-git pull failed: %w", err)
+}
+
+// goGitAuth returns the credentials for go-git: the SSH key from -ssh-key (or the SSH agent) with
+// -ssh, otherwise the API token as HTTPS basic auth. Anonymous access is used without a token.
+func goGitAuth(config Config, rec *RepoRecord) (transport.AuthMethod, error) {
+	if config.UseSSH {
+		if config.SSHKey == "" {
+			auth, err := gitssh.NewSSHAgentAuth("git")
+			if err != nil {
+				return nil, fmt.Errorf("failed to use SSH agent (set -ssh-key instead): %w", err)
+			}
+			return auth, nil
+		}
+		auth, err := gitssh.NewPublicKeysFromFile("git", config.SSHKey, os.Getenv("SSH_KEY_PASSPHRASE"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load SSH key: %w", err)
+		}
+		return auth, nil
+	}
+
+	if config.Token == "" {
+		return nil, nil
+	}
+	// GitHub accepts any user name with a token, GitLab expects oauth2
+	username := "x-access-token"
+	if rec.Platform == "gitlab" {
+		username = "oauth2"
+	}
+	return &githttp.BasicAuth{Username: username, Password: config.Token}, nil
+}
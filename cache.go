@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// APICache stores API responses on disk together with their ETag and Last-Modified validators, so
+// later runs can send conditional requests. A 304 Not Modified answer is served from the cache and
+// doesn't count against the GitHub rate limit.
+type APICache struct {
+	Dir string
+
+	mu          sync.Mutex
+	revalidated int // Responses confirmed unchanged with 304 Not Modified
+	stored      int // Responses written to the cache
+}
+
+// cachedResponse is the on-disk form of a cached API response
+type cachedResponse struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	Header       http.Header `json:"header"`
+	Body         []byte      `json:"body"`
+}
+
+// newAPICache creates the cache directory, defaulting to git-repo-downloader in the user cache directory
+func newAPICache(dir string) (*APICache, error) {
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find user cache directory: %w", err)
+		}
+		dir = filepath.Join(userCacheDir, "git-repo-downloader")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	return &APICache{Dir: dir}, nil
+}
+
+// transport returns a RoundTripper that answers GET requests from the cache when the server
+// confirms they are unchanged, or base itself for a nil cache
+func (c *APICache) transport(base http.RoundTripper) http.RoundTripper {
+	if c == nil {
+		return base
+	}
+	return &cacheTransport{cache: c, base: base}
+}
+
+// displaySummary prints how many API responses were served from and written to the cache
+func (c *APICache) displaySummary() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Printf("💾 API cache: %d responses unchanged (304), %d responses stored in %s\n", c.revalidated, c.stored, c.Dir)
+}
+
+// path returns the cache file of a request. Responses are keyed by URL, Accept header and
+// credentials, since different tokens can see different repositories.
+func (c *APICache) path(req *http.Request) string {
+	hash := sha256.New()
+	for _, part := range []string{
+		req.Method,
+		req.URL.String(),
+		req.Header.Get("Accept"),
+		req.Header.Get("Authorization"),
+		req.Header.Get("Private-Token"),
+	} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return filepath.Join(c.Dir, hex.EncodeToString(hash.Sum(nil))+".json")
+}
+
+// load reads the cached response of a request, if any
+func (c *APICache) load(path string) *cachedResponse {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(content, &cached); err != nil {
+		return nil
+	}
+	return &cached
+}
+
+// store writes a response to the cache, replacing the file atomically
+func (c *APICache) store(path string, cached cachedResponse) error {
+	content, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	c.stored++
+	c.mu.Unlock()
+	return nil
+}
+
+// cacheTransport adds conditional request headers from the cache and stores new responses
+type cacheTransport struct {
+	cache *APICache
+	base  http.RoundTripper
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return t.base.RoundTrip(req)
+	}
+
+	path := t.cache.path(req)
+	cached := t.cache.load(path)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		t.cache.mu.Lock()
+		t.cache.revalidated++
+		t.cache.mu.Unlock()
+		return cached.response(req), nil
+	}
+
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if resp.StatusCode != http.StatusOK || (etag == "" && lastModified == "") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// A broken cache only costs full requests, so failures to store are not fatal
+	t.cache.store(path, cachedResponse{
+		URL:          req.URL.String(),
+		ETag:         etag,
+		LastModified: lastModified,
+		Header:       resp.Header,
+		Body:         body,
+	})
+	return resp, nil
+}
+
+// response rebuilds the cached HTTP response. X-From-Cache tells go-github not to take the
+// stale rate limit headers into account.
+func (c *cachedResponse) response(req *http.Request) *http.Response {
+	header := c.Header.Clone()
+	header.Set("X-From-Cache", "1")
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
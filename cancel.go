@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// newRunContext returns the context of a download run. It is canceled on SIGINT or SIGTERM and
+// when -total-timeout expires. After the first signal the default handling is restored, so a
+// second Ctrl-C quits immediately.
+func newRunContext(config Config) (context.Context, context.CancelFunc) {
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signalCtx.Done()
+		stop()
+	}()
+
+	ctx, cancel := signalCtx, stop
+	if config.TotalTimeout > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(signalCtx, config.TotalTimeout)
+		cancel = func() {
+			cancelTimeout()
+			stop()
+		}
+	}
+	return ctx, cancel
+}
+
+// cancelReason describes why the run context ended
+func cancelReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "total timeout reached"
+	}
+	return "interrupted"
+}
+
+// markNotStarted marks the records that were never processed because the run ended early
+func markNotStarted(records []*RepoRecord, reason string) {
+	for _, rec := range records {
+		if rec.Outcome == "" {
+			rec.Outcome = outcomeNotStarted
+			rec.Reason = reason
+		}
+	}
+}
+
+// displayInterruptedSummary lists which repositories were completed, in progress or not started
+// when the run was interrupted or timed out
+func displayInterruptedSummary(records []*RepoRecord, reason string) {
+	var completed, inProgress, notStarted []string
+	for _, rec := range records {
+		switch rec.Outcome {
+		case outcomeCloned, outcomeUpdated, outcomeExists:
+			completed = append(completed, rec.Name)
+		case outcomeCanceled:
+			inProgress = append(inProgress, rec.Name)
+		case outcomeNotStarted:
+			notStarted = append(notStarted, rec.Name)
+		}
+	}
+
+	fmt.Printf("\n⚠️  Run stopped early (%s)\n", reason)
+	fmt.Printf("📊 Summary:\n")
+	fmt.Printf("   - Completed: %d\n", len(completed))
+	fmt.Printf("   - In progress (canceled, partial clone removed): %d\n", len(inProgress))
+	fmt.Printf("   - Not started: %d\n", len(notStarted))
+	fmt.Printf("   - Failed: %d\n", countOutcome(records, outcomeFailed))
+
+	for _, group := range []struct {
+		title string
+		names []string
+	}{
+		{"Completed", completed},
+		{"In progress", inProgress},
+		{"Not started", notStarted},
+	} {
+		if len(group.names) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s:\n", group.title)
+		for _, name := range group.names {
+			fmt.Printf("   - %s\n", name)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"gopkg.in/yaml.v3"
+)
+
+// catalogFileName is the name of the catalog file expected at the root of each repository
+const catalogFileName = ".catalog.yml"
+
+type CatalogInfo struct {
+	RepoName    string
+	RepoPath    string
+	CatalogPath string
+	HasCatalog  bool
+	Catalog     *CatalogYAML // Parsed catalog, nil if missing or invalid
+	ParseError  error        // Error reading or parsing the catalog, if any
+}
+
+// CatalogYAML represents the structure of .catalog.yml files
+type CatalogYAML struct {
+	Version   string `yaml:"version"`
+	Type      string `yaml:"type"`
+	Component struct {
+		Name        string   `yaml:"name"`
+		Service     string   `yaml:"service"`
+		Team        string   `yaml:"team"`
+		Description string   `yaml:"description"`
+		Tags        []string `yaml:"tags"`
+		Lifecycle   string   `yaml:"lifecycle"`
+		Kafka       struct {
+			Consumer struct {
+				Groups []string `yaml:"groups"`
+				Topics []string `yaml:"topics"`
+			} `yaml:"consumer"`
+			Producer struct {
+				Topics []string `yaml:"topics"`
+			} `yaml:"producer"`
+		} `yaml:"kafka"`
+	} `yaml:"component"`
+}
+
+// parseCatalog parses the raw content of a .catalog.yml file
+func parseCatalog(content []byte) (*CatalogYAML, error) {
+	var catalog CatalogYAML
+	if err := yaml.Unmarshal(content, &catalog); err != nil {
+		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	return &catalog, nil
+}
+
+// readCatalogFile reads and parses a .catalog.yml file from disk
+func readCatalogFile(path string) (*CatalogYAML, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", catalogFileName, err)
+	}
+	return parseCatalog(content)
+}
+
+// isBareRepository reports whether path is a bare git repository, such as a mirror clone
+func isBareRepository(path string) bool {
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		return false
+	}
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// isGitRepository reports whether path is the top level of a git repository, with or without a working tree
+func isGitRepository(path string) bool {
+	if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+		return true
+	}
+	return isBareRepository(path)
+}
+
+// readRepoCatalog reads the catalog of a local repository, taking it from HEAD for bare repositories
+// and when it isn't in the working tree, e.g. because sparse checkout left it out. found is false
+// when the repository has no catalog.
+func readRepoCatalog(repoPath string) (found bool, catalog *CatalogYAML, err error) {
+	if isBareRepository(repoPath) {
+		return readCatalogFromHead(repoPath)
+	}
+
+	catalogPath := filepath.Join(repoPath, catalogFileName)
+	if _, err := os.Stat(catalogPath); err != nil {
+		if !isGitRepository(repoPath) {
+			return false, nil, nil
+		}
+		return readCatalogFromHead(repoPath)
+	}
+	catalog, err = readCatalogFile(catalogPath)
+	return true, catalog, err
+}
+
+// readCatalogFromHead reads the catalog committed at HEAD, for repositories without a checked-out working tree
+func readCatalogFromHead(repoPath string) (found bool, catalog *CatalogYAML, err error) {
+	content, found, err := readFileFromHead(repoPath, catalogFileName)
+	if !found || err != nil {
+		return found, nil, err
+	}
+	catalog, err = parseCatalog(content)
+	return true, catalog, err
+}
+
+// readFileFromHead reads a file committed at HEAD of a local repository with go-git. found is false
+// when HEAD has no such file, or no commit yet.
+func readFileFromHead(repoPath, name string) (content []byte, found bool, err error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open repository: %w", err)
+	}
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read HEAD commit: %w", err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read HEAD tree: %w", err)
+	}
+	entry, err := tree.FindEntry(name)
+	if errors.Is(err, object.ErrEntryNotFound) || errors.Is(err, object.ErrDirectoryNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+
+	var blob string
+	file, err := tree.TreeEntryFile(entry)
+	if err == nil {
+		blob, err = file.Contents()
+	}
+	if errors.Is(err, plumbing.ErrObjectNotFound) || errors.Is(err, object.ErrFileNotFound) {
+		// Partial clones (-filter, sparse checkout) are made by the git binary, which fetches the
+		// blobs they left out on demand
+		blob, err = gitOutput(repoPath, nil, "cat-file", "blob", "HEAD:"+name)
+	}
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return []byte(blob), true, nil
+}
+
+// scanForCatalogFiles scans all repositories in the target directory for .catalog.yml files
+func scanForCatalogFiles(targetDir string) ([]CatalogInfo, error) {
+	var catalogInfo []CatalogInfo
+
+	// Read all entries in the target directory
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		repoName := entry.Name()
+		repoPath := filepath.Join(targetDir, repoName)
+		catalogPath := filepath.Join(repoPath, catalogFileName)
+		if isBareRepository(repoPath) {
+			// Mirrors are stored as name.git and have no working tree
+			repoName = strings.TrimSuffix(repoName, ".git")
+			catalogPath = fmt.Sprintf("%s (HEAD:%s)", repoPath, catalogFileName)
+		}
+
+		info := CatalogInfo{
+			RepoName:    repoName,
+			RepoPath:    repoPath,
+			CatalogPath: catalogPath,
+		}
+
+		// Check if .catalog.yml exists and parse it
+		info.HasCatalog, info.Catalog, info.ParseError = readRepoCatalog(repoPath)
+		if _, err := os.Stat(catalogPath); info.HasCatalog && err != nil {
+			// Left out of the working tree by sparse checkout, read from HEAD
+			info.CatalogPath = fmt.Sprintf("%s (HEAD:%s)", repoPath, catalogFileName)
+		}
+
+		catalogInfo = append(catalogInfo, info)
+	}
+
+	return catalogInfo, nil
+}
+
+// displayCatalogResults displays the results of the catalog file scan
+func displayCatalogResults(catalogInfo []CatalogInfo) {
+	writeCatalogResults(os.Stdout, catalogInfo)
+}
+
+// writeCatalogResults writes the results of the catalog file scan
+func writeCatalogResults(w io.Writer, catalogInfo []CatalogInfo) {
+	fmt.Fprintf(w, "\nCatalog File Scan Results\n")
+	fmt.Fprintf(w, "=========================\n")
+
+	reposWithCatalog := 0
+	reposWithoutCatalog := 0
+	reposWithInvalidCatalog := 0
+	lifecycles := make(map[string]int)
+
+	fmt.Fprintf(w, "Repository Analysis:\n")
+	fmt.Fprintf(w, "--------------------\n")
+
+	for _, info := range catalogInfo {
+		switch {
+		case !info.HasCatalog:
+			fmt.Fprintf(w, "❌ %s - .catalog.yml missing\n", info.RepoName)
+			reposWithoutCatalog++
+		case info.ParseError != nil:
+			fmt.Fprintf(w, "⚠️  %s - .catalog.yml invalid: %v\n", info.RepoName, info.ParseError)
+			reposWithCatalog++
+			reposWithInvalidCatalog++
+		default:
+			c := info.Catalog.Component
+			fmt.Fprintf(w, "✅ %s - .catalog.yml found\n", info.RepoName)
+			fmt.Fprintf(w, "     Team: %s | Service: %s | Lifecycle: %s | Type: %s\n",
+				orDash(c.Team), orDash(c.Service), orDash(c.Lifecycle), orDash(info.Catalog.Type))
+			fmt.Fprintf(w, "     Tags: %s\n", orDash(strings.Join(c.Tags, ", ")))
+			reposWithCatalog++
+			lifecycles[orDash(c.Lifecycle)]++
+		}
+	}
+
+	fmt.Fprintf(w, "\nSummary:\n")
+	fmt.Fprintf(w, "--------\n")
+	fmt.Fprintf(w, "Total repositories scanned: %d\n", len(catalogInfo))
+	fmt.Fprintf(w, "Repositories with .catalog.yml: %d\n", reposWithCatalog)
+	fmt.Fprintf(w, "Repositories with invalid .catalog.yml: %d\n", reposWithInvalidCatalog)
+	fmt.Fprintf(w, "Repositories missing .catalog.yml: %d\n", reposWithoutCatalog)
+
+	if len(lifecycles) > 0 {
+		fmt.Fprintf(w, "\n📊 Lifecycle breakdown:\n")
+		names := make([]string, 0, len(lifecycles))
+		for name := range lifecycles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "   - %s: %d\n", name, lifecycles[name])
+		}
+	}
+
+	if reposWithInvalidCatalog > 0 {
+		fmt.Fprintf(w, "\n⚠️  Repositories with invalid .catalog.yml files:\n")
+		for _, info := range catalogInfo {
+			if info.HasCatalog && info.ParseError != nil {
+				fmt.Fprintf(w, "   - %s (%s): %v\n", info.RepoName, info.CatalogPath, info.ParseError)
+			}
+		}
+	}
+
+	if reposWithoutCatalog > 0 {
+		fmt.Fprintf(w, "\n⚠️  Repositories missing .catalog.yml files:\n")
+		for _, info := range catalogInfo {
+			if !info.HasCatalog {
+				fmt.Fprintf(w, "   - %s\n", info.RepoName)
+			}
+		}
+	}
+
+	if reposWithCatalog > 0 {
+		fmt.Fprintf(w, "\n📋 Repositories with .catalog.yml files:\n")
+		for _, info := range catalogInfo {
+			if info.HasCatalog {
+				fmt.Fprintf(w, "   - %s (%s)\n", info.RepoName, info.CatalogPath)
+			}
+		}
+	}
+}
+
+// orDash returns "-" for empty values so that report columns stay aligned
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/xanzy/go-gitlab"
+)
+
+// defaultCatalogTemplate is the template used for generated .catalog.yml stubs unless -template is given
+const defaultCatalogTemplate = `---
+version: '1'
+type: {{ quote .Type }}
+component:
+  name: {{ quote .Name }}
+  service: {{ quote .Service }}
+  team: {{ quote .Team }}
+  description: {{ quote .Description }}
+  tags: []
+  lifecycle: {{ quote .Lifecycle }}
+`
+
+// catalogStubMessage is used as commit message and pull/merge request title
+const catalogStubMessage = "Add .catalog.yml"
+
+// catalogStubBody is the description of the generated pull/merge requests
+const catalogStubBody = "This adds a generated `.catalog.yml` stub so the repository shows up in the service catalog.\n\n" +
+	"Please review the team, lifecycle, type and description before merging."
+
+// CatalogStubData holds the values available to the catalog template
+type CatalogStubData struct {
+	Repository  string
+	Name        string
+	Service     string
+	Team        string
+	Description string
+	Type        string
+	Lifecycle   string
+}
+
+// catalogStubOptions configures catalog stub generation
+type catalogStubOptions struct {
+	Platform  string
+	Token     string
+	GitLabURL string
+	Team      string
+	Type      string
+	Lifecycle string
+	Branch    string
+	DryRun    bool
+	Template  *template.Template
+}
+
+// runCatalogStubs implements the catalog-stubs command
+func runCatalogStubs(args []string) int {
+	var opts catalogStubOptions
+	fs := flag.NewFlagSet("catalog-stubs", flag.ExitOnError)
+	dir := fs.String("dir", "./repositories", "Directory containing the downloaded repositories")
+	fs.StringVar(&opts.Platform, "platform", "", "Platform to open pull/merge requests on: github or gitlab (required unless -dry-run)")
+	fs.StringVar(&opts.Token, "token", "", "Personal access token used to open pull/merge requests (required unless -dry-run)")
+	fs.StringVar(&opts.GitLabURL, "gitlab-url", "https://gitlab.com", "GitLab instance URL (for self-hosted)")
+	fs.StringVar(&opts.Team, "team", "", "Team to use when it can't be inferred from CODEOWNERS")
+	fs.StringVar(&opts.Type, "type", "microservice", "Catalog type of the generated stubs")
+	fs.StringVar(&opts.Lifecycle, "lifecycle", "experimental", "Lifecycle of the generated stubs")
+	fs.StringVar(&opts.Branch, "branch", "add-catalog-yml", "Branch to commit the stub on")
+	templatePath := fs.String("template", "", "Go text/template file to render stubs with (default: built-in template)")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "Only print the diffs that would be committed")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: git-repo-downloader catalog-stubs [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Generates a .catalog.yml for every downloaded repository missing one, commits it on a branch\n")
+		fmt.Fprintf(fs.Output(), "and opens a GitHub pull request or GitLab merge request.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	opts.Platform = strings.ToLower(opts.Platform)
+	if !opts.DryRun {
+		if opts.Platform != "github" && opts.Platform != "gitlab" {
+			fmt.Fprintf(os.Stderr, "Error: -platform must be 'github' or 'gitlab' (or use -dry-run)\n")
+			return exitError
+		}
+		if opts.Token == "" {
+			fmt.Fprintf(os.Stderr, "Error: -token is required to open pull/merge requests (or use -dry-run)\n")
+			return exitError
+		}
+	}
+
+	templateText := defaultCatalogTemplate
+	if *templatePath != "" {
+		content, err := os.ReadFile(*templatePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read template: %v\n", err)
+			return exitError
+		}
+		templateText = string(content)
+	}
+	tmpl, err := template.New("catalog").Funcs(template.FuncMap{"quote": yamlQuote}).Parse(templateText)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid template: %v\n", err)
+		return exitError
+	}
+	opts.Template = tmpl
+
+	catalogs, err := scanForCatalogFiles(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	var missing []CatalogInfo
+	for _, info := range catalogs {
+		if !info.HasCatalog {
+			missing = append(missing, info)
+		}
+	}
+
+	fmt.Printf("📋 Found %d repositories missing .catalog.yml\n", len(missing))
+	if opts.DryRun {
+		fmt.Printf("🔍 Dry run: no branches will be pushed and no pull/merge requests opened\n")
+	}
+	fmt.Println()
+
+	ctx := context.Background()
+	created, skipped, failed := 0, 0, 0
+	for i, info := range missing {
+		fmt.Printf("[%d/%d] %s\n", i+1, len(missing), info.RepoName)
+
+		data, source := inferCatalogStubData(info, opts)
+		content, err := renderCatalogStub(opts.Template, data)
+		if err != nil {
+			fmt.Printf("  ❌ Error: %v\n\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("  Team: %s (%s)\n", orDash(data.Team), source)
+
+		if opts.DryRun {
+			fmt.Print(newFileDiff(catalogFileName, content))
+			fmt.Println()
+			continue
+		}
+
+		url, err := createCatalogStub(ctx, info.RepoPath, content, opts)
+		var skip *stubSkippedError
+		if errors.As(err, &skip) {
+			fmt.Printf("  ⏭️  Skipped: %s\n\n", skip.Reason)
+			skipped++
+			continue
+		}
+		if err != nil {
+			fmt.Printf("  ❌ Error: %v\n\n", err)
+			failed++
+			continue
+		}
+		fmt.Printf("  ✅ Opened: %s\n\n", url)
+		created++
+	}
+
+	fmt.Printf("Summary:\n")
+	fmt.Printf("--------\n")
+	fmt.Printf("Repositories missing .catalog.yml: %d\n", len(missing))
+	if !opts.DryRun {
+		fmt.Printf("Pull/merge requests opened: %d\n", created)
+		fmt.Printf("Skipped: %d\n", skipped)
+	}
+	fmt.Printf("Failures: %d\n", failed)
+
+	if failed > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// inferCatalogStubData fills the template values for a repository and reports where the team came from
+func inferCatalogStubData(info CatalogInfo, opts catalogStubOptions) (CatalogStubData, string) {
+	name := strings.TrimSuffix(info.RepoName, ".git")
+	data := CatalogStubData{
+		Repository:  name,
+		Name:        name,
+		Service:     name,
+		Description: "TODO: describe " + name,
+		Type:        opts.Type,
+		Lifecycle:   opts.Lifecycle,
+	}
+
+	codeowners, err := readCodeowners(info.RepoPath)
+	if err == nil && codeowners != nil {
+		if owner := codeowners.DefaultOwner(); owner != "" {
+			data.Team = ownerTeamName(owner)
+			return data, "from " + codeowners.Location
+		}
+	}
+
+	if opts.Team != "" {
+		data.Team = opts.Team
+		return data, "from -team"
+	}
+	return data, "unknown, please fill in"
+}
+
+// renderCatalogStub renders the template and checks that the result is a valid catalog
+func renderCatalogStub(tmpl *template.Template, data CatalogStubData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render template: %w", err)
+	}
+	if _, err := parseCatalog([]byte(b.String())); err != nil {
+		return "", fmt.Errorf("generated catalog is invalid: %w", err)
+	}
+	return b.String(), nil
+}
+
+// yamlQuote renders a string as a single-quoted YAML scalar
+func yamlQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// newFileDiff renders a unified diff adding a new file with the given content
+func newFileDiff(name, content string) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  --- /dev/null\n")
+	fmt.Fprintf(&b, "  +++ b/%s\n", name)
+	fmt.Fprintf(&b, "  @@ -0,0 +1,%d @@\n", len(lines))
+	for _, line := range lines {
+		fmt.Fprintf(&b, "  +%s\n", line)
+	}
+	return b.String()
+}
+
+// stubSkippedError reports that no stub was proposed for a repository because it isn't needed
+// or was already proposed
+type stubSkippedError struct {
+	Reason string
+}
+
+func (e *stubSkippedError) Error() string {
+	return e.Reason
+}
+
+// createCatalogStub commits the stub on a new branch without touching the working tree,
+// pushes it and opens a pull/merge request, returning its URL
+func createCatalogStub(ctx context.Context, repoPath, content string, opts catalogStubOptions) (string, error) {
+	remote, err := gitOutput(repoPath, nil, "remote", "get-url", "origin")
+	if err != nil {
+		return "", err
+	}
+	_, remotePath, err := parseRemoteURL(remote)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := gitOutput(repoPath, nil, "fetch", "--quiet", "origin"); err != nil {
+		return "", err
+	}
+
+	base, err := defaultBranch(repoPath)
+	if err != nil {
+		return "", err
+	}
+	baseCommit, err := resolveBranchCommit(repoPath, base)
+	if err != nil {
+		return "", err
+	}
+
+	// The local clone may be older than the remote, which may have gained a catalog since
+	if _, err := gitOutput(repoPath, nil, "cat-file", "-e", baseCommit+":"+catalogFileName); err == nil {
+		return "", &stubSkippedError{Reason: fmt.Sprintf("origin/%s already has %s, update the local clone", base, catalogFileName)}
+	}
+
+	// Don't propose the stub twice when catalog-stubs is run again: the branch can't be pushed over
+	// and a pull/merge request from it may already be open
+	heads, err := gitOutput(repoPath, nil, "ls-remote", "--heads", "origin", "refs/heads/"+opts.Branch)
+	if err != nil {
+		return "", err
+	}
+	if heads != "" {
+		url, err := findOpenCatalogStubRequest(ctx, remotePath, opts)
+		if err != nil {
+			return "", err
+		}
+		if url != "" {
+			return "", &stubSkippedError{Reason: "already open: " + url}
+		}
+		return "", &stubSkippedError{Reason: fmt.Sprintf("branch %s already exists on origin, delete it or use -branch", opts.Branch)}
+	}
+
+	commit, err := commitFileOnto(repoPath, baseCommit, catalogFileName, content, catalogStubMessage)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("  Pushing %s to branch %s\n", commit[:12], opts.Branch)
+	// Mirror clones push all refs by default, which can't be combined with a refspec
+	noMirror := []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=remote.origin.mirror", "GIT_CONFIG_VALUE_0=false"}
+	if _, err := gitOutput(repoPath, noMirror, "push", "--quiet", "origin", commit+":refs/heads/"+opts.Branch); err != nil {
+		return "", err
+	}
+
+	switch opts.Platform {
+	case "github":
+		owner, repo, ok := strings.Cut(remotePath, "/")
+		if !ok {
+			return "", fmt.Errorf("unexpected GitHub repository path: %s", remotePath)
+		}
+		client := newGitHubClient(ctx, opts.Token, nil)
+		pr, _, err := client.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
+			Title: github.String(catalogStubMessage),
+			Head:  github.String(opts.Branch),
+			Base:  github.String(base),
+			Body:  github.String(catalogStubBody),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to open pull request: %w", err)
+		}
+		return pr.GetHTMLURL(), nil
+	case "gitlab":
+		client, err := newGitLabClient(opts.Token, opts.GitLabURL, nil)
+		if err != nil {
+			return "", err
+		}
+		mr, _, err := client.MergeRequests.CreateMergeRequest(remotePath, &gitlab.CreateMergeRequestOptions{
+			Title:              gitlab.String(catalogStubMessage),
+			Description:        gitlab.String(catalogStubBody),
+			SourceBranch:       gitlab.String(opts.Branch),
+			TargetBranch:       gitlab.String(base),
+			RemoveSourceBranch: gitlab.Bool(true),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return "", fmt.Errorf("failed to open merge request: %w", err)
+		}
+		return mr.WebURL, nil
+	}
+	return "", fmt.Errorf("unsupported platform: %s", opts.Platform)
+}
+
+// findOpenCatalogStubRequest returns the URL of an open pull/merge request from the stub branch,
+// or "" if there is none
+func findOpenCatalogStubRequest(ctx context.Context, remotePath string, opts catalogStubOptions) (string, error) {
+	switch opts.Platform {
+	case "github":
+		owner, repo, ok := strings.Cut(remotePath, "/")
+		if !ok {
+			return "", fmt.Errorf("unexpected GitHub repository path: %s", remotePath)
+		}
+		client := newGitHubClient(ctx, opts.Token, nil)
+		prs, _, err := client.PullRequests.List(ctx, owner, repo, &github.PullRequestListOptions{
+			State: "open",
+			Head:  owner + ":" + opts.Branch,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to list pull requests: %w", err)
+		}
+		if len(prs) > 0 {
+			return prs[0].GetHTMLURL(), nil
+		}
+	case "gitlab":
+		client, err := newGitLabClient(opts.Token, opts.GitLabURL, nil)
+		if err != nil {
+			return "", err
+		}
+		mrs, _, err := client.MergeRequests.ListProjectMergeRequests(remotePath, &gitlab.ListProjectMergeRequestsOptions{
+			State:        gitlab.String("opened"),
+			SourceBranch: gitlab.String(opts.Branch),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return "", fmt.Errorf("failed to list merge requests: %w", err)
+		}
+		if len(mrs) > 0 {
+			return mrs[0].WebURL, nil
+		}
+	}
+	return "", nil
+}
+
+// defaultBranch returns the remote's default branch, falling back to the local HEAD branch
+func defaultBranch(repoPath string) (string, error) {
+	if ref, err := gitOutput(repoPath, nil, "symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/"), nil
+	}
+	ref, err := gitOutput(repoPath, nil, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("could not determine default branch: %w", err)
+	}
+	return ref, nil
+}
+
+// resolveBranchCommit resolves a branch to a commit, preferring the remote-tracking branch
+func resolveBranchCommit(repoPath, branch string) (string, error) {
+	for _, ref := range []string{"refs/remotes/origin/" + branch, "refs/heads/" + branch} {
+		if commit, err := gitOutput(repoPath, nil, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+			return commit, nil
+		}
+	}
+	return "", fmt.Errorf("branch %s not found", branch)
+}
+
+// commitFileOnto creates a commit adding a file on top of parent using a temporary index,
+// leaving the working tree and current branch untouched
+func commitFileOnto(repoPath, parent, name, content, message string) (string, error) {
+	contentFile, err := os.CreateTemp("", "catalog-stub-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(contentFile.Name())
+	if _, err := contentFile.WriteString(content); err != nil {
+		contentFile.Close()
+		return "", err
+	}
+	contentFile.Close()
+
+	indexFile := contentFile.Name() + ".index"
+	defer os.Remove(indexFile)
+	env := []string{"GIT_INDEX_FILE=" + indexFile}
+
+	blob, err := gitOutput(repoPath, nil, "hash-object", "-w", contentFile.Name())
+	if err != nil {
+		return "", err
+	}
+	if _, err := gitOutput(repoPath, env, "read-tree", parent); err != nil {
+		return "", err
+	}
+	if _, err := gitOutput(repoPath, env, "update-index", "--add", "--cacheinfo", "100644,"+blob+","+name); err != nil {
+		return "", err
+	}
+	tree, err := gitOutput(repoPath, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	return gitOutput(repoPath, nil, "commit-tree", tree, "-p", parent, "-m", message)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runTestGit runs a git command for test setup and returns its trimmed output
+func runTestGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// setTestGitIdentity lets tests commit without a configured git identity
+func setTestGitIdentity(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "Test")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "test@example.com")
+	}
+}
+
+// newTestRemote creates a bare repository with one commit on main containing the given files
+func newTestRemote(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	runTestGit(t, dir, "init", "--quiet", "--initial-branch=main", work)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runTestGit(t, work, "add", "--all")
+	runTestGit(t, work, "commit", "--quiet", "--allow-empty", "-m", "Initial commit")
+	remote := filepath.Join(dir, "remote.git")
+	runTestGit(t, dir, "clone", "--quiet", "--bare", work, remote)
+	return remote
+}
+
+func TestCreateCatalogStub(t *testing.T) {
+	setTestGitIdentity(t)
+
+	tests := []struct {
+		name       string
+		files      map[string]string
+		mirror     bool
+		setup      func(t *testing.T, remote string)
+		wantSkip   string
+		wantPushed bool
+	}{
+		{name: "clone", files: map[string]string{"README.md": "# api\n"}, wantPushed: true},
+		{name: "mirror clone", files: map[string]string{"README.md": "# api\n"}, mirror: true, wantPushed: true},
+		{
+			name:     "catalog added on the remote after cloning",
+			files:    map[string]string{"README.md": "# api\n"},
+			wantSkip: "origin/main already has .catalog.yml",
+			setup: func(t *testing.T, remote string) {
+				work := t.TempDir()
+				runTestGit(t, work, "clone", "--quiet", remote, ".")
+				if err := os.WriteFile(filepath.Join(work, catalogFileName), []byte("type: service\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				runTestGit(t, work, "add", catalogFileName)
+				runTestGit(t, work, "commit", "--quiet", "-m", "Add catalog")
+				runTestGit(t, work, "push", "--quiet", "origin", "main")
+			},
+		},
+		{
+			name:     "stub branch already pushed",
+			files:    map[string]string{"README.md": "# api\n"},
+			wantSkip: "branch add-catalog-yml already exists on origin",
+			setup: func(t *testing.T, remote string) {
+				runTestGit(t, remote, "branch", "add-catalog-yml", "main")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := newTestRemote(t, tt.files)
+			// parseRemoteURL needs a host, file://localhost/ is the local file system
+			remoteURL := "file://localhost" + filepath.ToSlash(remote)
+			local := filepath.Join(t.TempDir(), "api")
+			if tt.mirror {
+				local += ".git"
+				runTestGit(t, "", "clone", "--quiet", "--mirror", remoteURL, local)
+			} else {
+				runTestGit(t, "", "clone", "--quiet", remoteURL, local)
+			}
+			if tt.setup != nil {
+				tt.setup(t, remote)
+			}
+
+			// Without a platform nothing is opened, the error comes after the push
+			opts := catalogStubOptions{Branch: "add-catalog-yml"}
+			_, err := createCatalogStub(context.Background(), local, "type: service\n", opts)
+
+			if tt.wantSkip != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantSkip) {
+					t.Fatalf("createCatalogStub() error = %v, want skipped: %s", err, tt.wantSkip)
+				}
+				if _, ok := err.(*stubSkippedError); !ok {
+					t.Errorf("createCatalogStub() error = %T, want *stubSkippedError", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "unsupported platform") {
+				t.Fatalf("createCatalogStub() error = %v, want the push to succeed", err)
+			}
+			if tt.wantPushed {
+				content := runTestGit(t, remote, "cat-file", "blob", "add-catalog-yml:"+catalogFileName)
+				if content != "type: service" {
+					t.Errorf("pushed %s = %q", catalogFileName, content)
+				}
+				if parent := runTestGit(t, remote, "rev-parse", "add-catalog-yml^"); parent != runTestGit(t, remote, "rev-parse", "main") {
+					t.Errorf("stub commit isn't based on main")
+				}
+			}
+		})
+	}
+}
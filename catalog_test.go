@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadFileFromHead(t *testing.T) {
+	setTestGitIdentity(t)
+	remote := newTestRemote(t, map[string]string{catalogFileName: "type: service\n", "README.md": "# api\n"})
+	runTestGit(t, remote, "config", "uploadpack.allowFilter", "true")
+	remoteURL := "file://" + filepath.ToSlash(remote)
+
+	tests := []struct {
+		name      string
+		clone     func(t *testing.T, local string)
+		file      string
+		wantFound bool
+	}{
+		{
+			name:      "clone",
+			clone:     func(t *testing.T, local string) { runTestGit(t, "", "clone", "--quiet", remoteURL, local) },
+			file:      catalogFileName,
+			wantFound: true,
+		},
+		{
+			name:      "mirror clone",
+			clone:     func(t *testing.T, local string) { runTestGit(t, "", "clone", "--quiet", "--mirror", remoteURL, local) },
+			file:      catalogFileName,
+			wantFound: true,
+		},
+		{
+			// The catalog blob isn't fetched by the partial clone and has to be fetched on demand
+			name: "partial sparse clone",
+			clone: func(t *testing.T, local string) {
+				runTestGit(t, "", "clone", "--quiet", "--no-checkout", "--filter=blob:none", remoteURL, local)
+				runTestGit(t, local, "sparse-checkout", "set", "--no-cone", "/README.md")
+				runTestGit(t, local, "checkout", "--quiet", "main")
+			},
+			file:      catalogFileName,
+			wantFound: true,
+		},
+		{
+			name:  "file not at HEAD",
+			clone: func(t *testing.T, local string) { runTestGit(t, "", "clone", "--quiet", remoteURL, local) },
+			file:  ".gitmodules",
+		},
+		{
+			name:  "empty repository",
+			clone: func(t *testing.T, local string) { runTestGit(t, "", "init", "--quiet", local) },
+			file:  catalogFileName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := filepath.Join(t.TempDir(), "api")
+			tt.clone(t, local)
+
+			content, found, err := readFileFromHead(local, tt.file)
+			if err != nil {
+				t.Fatalf("readFileFromHead() error = %v", err)
+			}
+			if found != tt.wantFound {
+				t.Fatalf("readFileFromHead() found = %v, want %v", found, tt.wantFound)
+			}
+			if tt.wantFound && strings.TrimSpace(string(content)) != "type: service" {
+				t.Errorf("readFileFromHead() = %q", content)
+			}
+		})
+	}
+}
+
+func TestReadRepoCatalogOutsideRepository(t *testing.T) {
+	// A directory without .git is never looked up in the repository of a parent directory
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	found, catalog, err := readRepoCatalog(dir)
+	if found || catalog != nil || err != nil {
+		t.Errorf("readRepoCatalog() = %v, %v, %v, want nothing", found, catalog, err)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// validateCloneOptions checks that the clone options are valid and can be combined
+func validateCloneOptions(config Config) error {
+	switch config.Backend {
+	case backendExec, backendGoGit:
+	default:
+		return fmt.Errorf("invalid backend '%s'. Must be '%s' or '%s'", config.Backend, backendExec, backendGoGit)
+	}
+	if config.Depth < 0 {
+		return fmt.Errorf("-depth must be a positive number of commits")
+	}
+	switch config.Filter {
+	case "", "blob:none", "tree:0":
+	default:
+		return fmt.Errorf("invalid filter '%s'. Must be 'blob:none' or 'tree:0'", config.Filter)
+	}
+	if config.Mirror && config.Depth > 0 {
+		return fmt.Errorf("-depth cannot be used with -mirror, mirrors always contain the full history")
+	}
+	if config.Unshallow && config.Depth > 0 {
+		return fmt.Errorf("-unshallow cannot be used with -depth")
+	}
+	if config.Mirror && config.Unshallow {
+		return fmt.Errorf("-unshallow cannot be used with -mirror, mirrors always contain the full history")
+	}
+	if config.Mirror && config.SingleBranch {
+		return fmt.Errorf("-single-branch cannot be used with -mirror, mirrors always contain all refs")
+	}
+	if config.CloneTimeout < 0 {
+		return fmt.Errorf("-clone-timeout must not be negative")
+	}
+	switch config.LFS {
+	case "", "fetch", "skip":
+	default:
+		return fmt.Errorf("invalid lfs mode '%s'. Must be 'fetch' or 'skip'", config.LFS)
+	}
+	if config.Mirror && config.Submodules {
+		return fmt.Errorf("-submodules cannot be used with -mirror, mirrors have no working tree")
+	}
+	if config.Mirror && config.Sparse.enabled() {
+		return fmt.Errorf("sparse checkout cannot be used with -mirror, mirrors have no working tree")
+	}
+	return nil
+}
+
+// localRepoPath returns where a repository is stored locally; mirrors are bare repositories named name.git
+func localRepoPath(config Config, repoName string) string {
+	if config.Mirror {
+		return filepath.Join(config.TargetDir, repoName+".git")
+	}
+	return filepath.Join(config.TargetDir, repoName)
+}
+
+// cloneRecord clones or updates the repository of a record and stores the outcome on it.
+// The clone is limited to -clone-timeout; when ctx itself ends the record is marked canceled.
+func cloneRecord(ctx context.Context, config Config, rec *RepoRecord) error {
+	started := time.Now()
+	config.Events.cloneStarted(config, rec)
+	ctx = config.Progress.begin(ctx, rec)
+	defer config.Progress.end(rec)
+	cloneCtx := ctx
+	if config.CloneTimeout > 0 {
+		var cancel context.CancelFunc
+		cloneCtx, cancel = context.WithTimeout(ctx, config.CloneTimeout)
+		defer cancel()
+	}
+
+	var outcome string
+	err := withRetry(cloneCtx, config.Retry, "cloning "+rec.Name, classifyCloneError, func() (err error) {
+		outcome, err = cloneRepository(cloneCtx, config, rec)
+		return err
+	})
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		outcome = outcomeCanceled
+		err = fmt.Errorf("%s", cancelReason(ctx))
+	case cloneCtx.Err() != nil:
+		err = fmt.Errorf("clone timed out after %s", config.CloneTimeout)
+	}
+
+	rec.Outcome = outcome
+	if err != nil {
+		rec.Reason = err.Error()
+	}
+	config.Events.cloneDone(rec, time.Since(started))
+	return err
+}
+
+// outcomeVerb describes a successful outcome for progress messages
+func outcomeVerb(outcome string) string {
+	if outcome == outcomeUpdated {
+		return "updated"
+	}
+	return "cloned"
+}
+
+// cloneRepository clones a repository into the target directory and returns the resulting outcome
+func cloneRepository(ctx context.Context, config Config, rec *RepoRecord) (string, error) {
+	repoPath := localRepoPath(config, rec.Name)
+	cloneURL := rec.CloneURL(config.UseSSH)
+	backend := newCloneBackend(config)
+
+	// Check if repository already exists. Clones without a valid HEAD are cloned again and only
+	// replaced once the fresh clone is complete.
+	_, statErr := os.Stat(repoPath)
+	if statErr == nil && hasBrokenHead(repoPath) {
+		fmt.Printf("  HEAD of %s doesn't resolve to a commit, cloning it again\n", repoPath)
+		rec.BrokenHead = true
+	}
+	if statErr == nil && !rec.BrokenHead {
+		if config.Mirror {
+			fmt.Printf("  Updating mirror at %s\n", repoPath)
+			if err := backend.UpdateMirror(ctx, config, rec, repoPath); err != nil {
+				return outcomeFailed, err
+			}
+			if err := completeClone(ctx, config, rec, repoPath); err != nil {
+				return outcomeFailed, err
+			}
+			return outcomeUpdated, nil
+		}
+		if isShallowRepository(repoPath) {
+			switch {
+			case config.Depth > 0:
+				fmt.Printf("  Updating shallow clone at %s (depth %d)\n", repoPath, config.Depth)
+			case config.Unshallow:
+				fmt.Printf("  Converting shallow clone at %s into a full clone\n", repoPath)
+			default:
+				fmt.Printf("  Updating shallow clone at %s\n", repoPath)
+			}
+			if err := backend.UpdateShallow(ctx, config, rec, repoPath); err != nil {
+				return outcomeFailed, err
+			}
+			if err := completeClone(ctx, config, rec, repoPath); err != nil {
+				return outcomeFailed, err
+			}
+			return outcomeUpdated, nil
+		}
+		fmt.Printf("  Repository already exists at %s, skipping...\n", repoPath)
+		detectRepoFeatures(rec, repoPath)
+		return outcomeExists, nil
+	}
+
+	// Clone the repository into a temporary sibling directory and move it into place once complete,
+	// so an interrupted clone never leaves a directory that later runs would skip
+	if config.Progress == nil {
+		fmt.Printf("  Cloning from: %s\n", cloneURL)
+		fmt.Printf("  Target path: %s\n", repoPath)
+	}
+
+	partialPath := partialRepoPath(repoPath)
+	if err := os.RemoveAll(partialPath); err != nil {
+		return outcomeFailed, fmt.Errorf("failed to remove leftover partial clone: %w", err)
+	}
+
+	var err error
+	if config.Sparse.enabled() {
+		// Sparse checkout always needs the git binary
+		err = sparseClone(ctx, config, rec, cloneURL, partialPath)
+	} else {
+		err = backend.Clone(ctx, config, rec, cloneURL, partialPath)
+	}
+	if err == nil {
+		// Submodules and LFS objects are part of the clone: if they fail, the clone is discarded
+		err = completeClone(ctx, config, rec, partialPath)
+	}
+	if err != nil {
+		os.RemoveAll(partialPath)
+		return outcomeFailed, err
+	}
+
+	if rec.BrokenHead {
+		err = replaceClone(partialPath, repoPath)
+	} else {
+		err = os.Rename(partialPath, repoPath)
+	}
+	if err != nil {
+		os.RemoveAll(partialPath)
+		return outcomeFailed, fmt.Errorf("failed to move clone into place: %w", err)
+	}
+	return outcomeCloned, nil
+}
+
+// cloneArgs builds the git clone arguments for the configured clone options
+func cloneArgs(config Config, cloneURL, repoPath string) []string {
+	// Stderr is captured for error classification, so progress has to be requested explicitly
+	args := []string{"clone", "--progress"}
+	if config.Mirror {
+		// A mirror clone is bare and maps all refs (branches, tags, notes) one to one
+		args = append(args, "--mirror")
+	}
+	if config.Depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", config.Depth))
+		if !config.SingleBranch {
+			// --depth implies --single-branch, so ask for the tips of all branches explicitly
+			args = append(args, "--no-single-branch")
+		}
+	}
+	if config.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if config.Filter != "" {
+		args = append(args, "--filter="+config.Filter)
+	}
+	return append(args, cloneURL, repoPath)
+}
+
+// isShallowRepository reports whether a local (non-bare) repository is a shallow clone.
+// Git records the boundary commits of shallow clones in .git/shallow.
+func isShallowRepository(repoPath string) bool {
+	_, err := os.Stat(filepath.Join(repoPath, ".git", "shallow"))
+	return err == nil
+}
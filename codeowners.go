@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// codeownersLocations lists where GitHub and GitLab look for a CODEOWNERS file, in lookup order
+var codeownersLocations = []string{
+	".github/CODEOWNERS",
+	".gitlab/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+}
+
+// CodeownersRule is a single pattern line of a CODEOWNERS file
+type CodeownersRule struct {
+	Pattern string
+	Owners  []string
+}
+
+// Codeowners is a parsed CODEOWNERS file
+type Codeowners struct {
+	Path     string // File the rules were read from, or the repository and HEAD:location
+	Location string // Location within the repository, e.g. .github/CODEOWNERS
+	Rules    []CodeownersRule
+}
+
+// readCodeowners finds and parses the CODEOWNERS file of a repository, returning nil if there is none.
+// Files missing from the working tree are read from HEAD, so mirrors and sparse checkouts are covered.
+func readCodeowners(repoPath string) (*Codeowners, error) {
+	inRepository := isGitRepository(repoPath)
+	for _, location := range codeownersLocations {
+		path := filepath.Join(repoPath, location)
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			if !inRepository {
+				continue
+			}
+			var found bool
+			content, found, err = readFileFromHead(repoPath, location)
+			if !found {
+				continue
+			}
+			path = fmt.Sprintf("%s (HEAD:%s)", repoPath, location)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return parseCodeowners(path, location, content), nil
+	}
+
+	return nil, nil
+}
+
+// parseCodeowners parses the content of a CODEOWNERS file
+func parseCodeowners(path, location string, content []byte) *Codeowners {
+	codeowners := &Codeowners{Path: path, Location: location}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// GitLab section headers ([Section] or ^[Section][2]) may list default owners after the brackets
+		if strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			end := strings.LastIndex(line, "]")
+			owners := strings.Fields(line[end+1:])
+			if len(owners) > 0 {
+				codeowners.Rules = append(codeowners.Rules, CodeownersRule{Pattern: "*", Owners: owners})
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		rule := CodeownersRule{Pattern: fields[0]}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break // Trailing comment
+			}
+			rule.Owners = append(rule.Owners, owner)
+		}
+		codeowners.Rules = append(codeowners.Rules, rule)
+	}
+	return codeowners
+}
+
+// Owners returns all distinct owners mentioned in the file
+func (c *Codeowners) Owners() []string {
+	var owners []string
+	for _, rule := range c.Rules {
+		owners = append(owners, rule.Owners...)
+	}
+	return uniqueStrings(owners)
+}
+
+// DefaultOwner returns the first owner of the catch-all rule, or the first owner in the file
+func (c *Codeowners) DefaultOwner() string {
+	for _, rule := range c.Rules {
+		if (rule.Pattern == "*" || rule.Pattern == "/**" || rule.Pattern == "/") && len(rule.Owners) > 0 {
+			return rule.Owners[0]
+		}
+	}
+	if owners := c.Owners(); len(owners) > 0 {
+		return owners[0]
+	}
+	return ""
+}
+
+// ownerTeamName turns a CODEOWNERS owner such as @org/platform-team into the team name platform-team
+func ownerTeamName(owner string) string {
+	owner = strings.TrimPrefix(owner, "@")
+	if i := strings.LastIndex(owner, "/"); i >= 0 {
+		owner = owner[i+1:]
+	}
+	return owner
+}
+
+// normalizeTeamName lowercases a team name and strips separators so "Platform Team" matches @org/platform-team
+func normalizeTeamName(team string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(team) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// teamMatchesOwners reports whether a catalog team corresponds to any CODEOWNERS owner
+func teamMatchesOwners(team string, owners []string) bool {
+	normalized := normalizeTeamName(team)
+	if normalized == "" {
+		return false
+	}
+
+	for _, owner := range owners {
+		name := normalizeTeamName(ownerTeamName(owner))
+		if name == normalized || name == normalized+"team" || normalized == name+"team" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTeamMatchesOwners(t *testing.T) {
+	tests := []struct {
+		team   string
+		owners []string
+		want   bool
+	}{
+		{"platform-team", []string{"@acme/platform-team"}, true},
+		{"Platform Team", []string{"@acme/platform-team"}, true},
+		{"platform", []string{"@acme/platform-team"}, true},
+		{"Payments Team", []string{"@acme/payments"}, true},
+		{"payments", []string{"@acme/billing", "@acme/payments"}, true},
+		{"payments", []string{"@jane"}, false},
+		{"payments", []string{"@acme/payment"}, false},
+		{"payments", nil, false},
+		{"", []string{"@acme/payments"}, false},
+		{"--", []string{"@acme/payments"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := teamMatchesOwners(tt.team, tt.owners); got != tt.want {
+			t.Errorf("teamMatchesOwners(%q, %q) = %v, want %v", tt.team, tt.owners, got, tt.want)
+		}
+	}
+}
+
+func TestReadCodeowners(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		wantPath     string
+		wantRules    []CodeownersRule
+		wantDefault  string
+		wantNoResult bool
+	}{
+		{
+			name:         "no CODEOWNERS",
+			files:        map[string]string{"README.md": "# repo\n"},
+			wantNoResult: true,
+		},
+		{
+			name: "GitHub file with comments",
+			files: map[string]string{".github/CODEOWNERS": "# Owners\n\n" +
+				"/docs/ @acme/docs\n" +
+				"* @acme/platform-team @jane # fallback\n"},
+			wantPath: ".github/CODEOWNERS",
+			wantRules: []CodeownersRule{
+				{Pattern: "/docs/", Owners: []string{"@acme/docs"}},
+				{Pattern: "*", Owners: []string{"@acme/platform-team", "@jane"}},
+			},
+			wantDefault: "@acme/platform-team",
+		},
+		{
+			name: "GitLab sections with default owners",
+			files: map[string]string{".gitlab/CODEOWNERS": "[Backend] @acme/backend\n" +
+				"/api/\n" +
+				"^[Docs][2] @acme/docs\n" +
+				"[Empty]\n"},
+			wantPath: ".gitlab/CODEOWNERS",
+			wantRules: []CodeownersRule{
+				{Pattern: "*", Owners: []string{"@acme/backend"}},
+				{Pattern: "/api/"},
+				{Pattern: "*", Owners: []string{"@acme/docs"}},
+			},
+			wantDefault: "@acme/backend",
+		},
+		{
+			name: "lookup order",
+			files: map[string]string{
+				"CODEOWNERS":         "/src/ @acme/root\n",
+				"docs/CODEOWNERS":    "* @acme/docs\n",
+				".gitlab/CODEOWNERS": "/lib/ @acme/gitlab\n",
+			},
+			wantPath:    ".gitlab/CODEOWNERS",
+			wantRules:   []CodeownersRule{{Pattern: "/lib/", Owners: []string{"@acme/gitlab"}}},
+			wantDefault: "@acme/gitlab",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			codeowners, err := readCodeowners(dir)
+			if err != nil {
+				t.Fatalf("readCodeowners() error = %v", err)
+			}
+			if tt.wantNoResult {
+				if codeowners != nil {
+					t.Errorf("readCodeowners() = %+v, want nil", codeowners)
+				}
+				return
+			}
+			if codeowners == nil {
+				t.Fatal("readCodeowners() = nil")
+			}
+			if want := filepath.Join(dir, tt.wantPath); codeowners.Path != want {
+				t.Errorf("Path = %s, want %s", codeowners.Path, want)
+			}
+			if codeowners.Location != tt.wantPath {
+				t.Errorf("Location = %s, want %s", codeowners.Location, tt.wantPath)
+			}
+			if !reflect.DeepEqual(codeowners.Rules, tt.wantRules) {
+				t.Errorf("Rules = %+v, want %+v", codeowners.Rules, tt.wantRules)
+			}
+			if got := codeowners.DefaultOwner(); got != tt.wantDefault {
+				t.Errorf("DefaultOwner() = %s, want %s", got, tt.wantDefault)
+			}
+		})
+	}
+}
+
+func TestReadCodeownersFromHead(t *testing.T) {
+	setTestGitIdentity(t)
+	remote := newTestRemote(t, map[string]string{"CODEOWNERS": "* @acme/platform-team\n", "README.md": "# api\n"})
+
+	tests := []struct {
+		name  string
+		clone func(t *testing.T, local string)
+	}{
+		{
+			name: "mirror clone",
+			clone: func(t *testing.T, local string) {
+				runTestGit(t, "", "clone", "--quiet", "--mirror", remote, local)
+			},
+		},
+		{
+			name: "sparse checkout without CODEOWNERS",
+			clone: func(t *testing.T, local string) {
+				runTestGit(t, "", "clone", "--quiet", "--no-checkout", remote, local)
+				runTestGit(t, local, "sparse-checkout", "set", "--no-cone", "/README.md")
+				runTestGit(t, local, "checkout", "--quiet", "main")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := filepath.Join(t.TempDir(), "api")
+			tt.clone(t, local)
+			if _, err := os.Stat(filepath.Join(local, "CODEOWNERS")); err == nil {
+				t.Fatal("CODEOWNERS is in the working tree")
+			}
+
+			codeowners, err := readCodeowners(local)
+			if err != nil {
+				t.Fatalf("readCodeowners() error = %v", err)
+			}
+			if codeowners == nil {
+				t.Fatal("readCodeowners() = nil, want the CODEOWNERS file at HEAD")
+			}
+			if codeowners.Location != "CODEOWNERS" || codeowners.DefaultOwner() != "@acme/platform-team" {
+				t.Errorf("readCodeowners() = %+v, want CODEOWNERS owned by @acme/platform-team", codeowners)
+			}
+		})
+	}
+}
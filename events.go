@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Output modes of -output
+const (
+	outputText = "text" // Human-readable progress on stdout
+	outputJSON = "json" // One JSON event per line on stdout, human-readable progress on stderr
+)
+
+// Event names of -output=json
+const (
+	eventRepoDiscovered = "repo_discovered"
+	eventCatalogChecked = "catalog_checked"
+	eventCloneStarted   = "clone_started"
+	eventCloneFinished  = "clone_finished"
+	eventCloneFailed    = "clone_failed"
+	eventSummary        = "summary"
+)
+
+// RepoEvent contains the fields shared by all events about a single repository
+type RepoEvent struct {
+	Event     string    `json:"event"`
+	Time      time.Time `json:"time"`
+	Platform  string    `json:"platform"`
+	Namespace string    `json:"namespace"`
+	Repo      string    `json:"repo"`
+}
+
+// RepoDiscoveredEvent is emitted for every repository found while listing
+type RepoDiscoveredEvent struct {
+	RepoEvent
+	ID            int64  `json:"id"`
+	WebURL        string `json:"web_url"`
+	CloneURL      string `json:"clone_url"`
+	DefaultBranch string `json:"default_branch"`
+	Visibility    string `json:"visibility"`
+	Archived      bool   `json:"archived"`
+	LocalPath     string `json:"local_path"`
+}
+
+// CatalogCheckedEvent is emitted for every repository whose .catalog.yml was checked in --prod mode
+type CatalogCheckedEvent struct {
+	RepoEvent
+	HasCatalog bool   `json:"has_catalog"`
+	Type       string `json:"type"`
+	Lifecycle  string `json:"lifecycle"`
+	Production bool   `json:"production"`
+	Error      string `json:"error,omitempty"`
+}
+
+// CloneStartedEvent is emitted before a repository is cloned or updated
+type CloneStartedEvent struct {
+	RepoEvent
+	CloneURL  string `json:"clone_url"`
+	LocalPath string `json:"local_path"`
+}
+
+// CloneFinishedEvent is emitted when a repository was cloned, updated or already existed
+type CloneFinishedEvent struct {
+	RepoEvent
+	LocalPath  string `json:"local_path"`
+	Outcome    string `json:"outcome"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// CloneFailedEvent is emitted when cloning or updating a repository failed or was canceled
+type CloneFailedEvent struct {
+	RepoEvent
+	LocalPath  string `json:"local_path"`
+	Outcome    string `json:"outcome"`
+	Error      string `json:"error"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// SummaryEvent is emitted once at the end of the run
+type SummaryEvent struct {
+	Event       string    `json:"event"`
+	Time        time.Time `json:"time"`
+	Discovered  int       `json:"discovered"`
+	Cloned      int       `json:"cloned"`
+	Updated     int       `json:"updated"`
+	Exists      int       `json:"exists"`
+	Skipped     int       `json:"skipped"`
+	Failed      int       `json:"failed"`
+	Canceled    int       `json:"canceled"`
+	NotStarted  int       `json:"not_started"`
+	WouldClone  int       `json:"would_clone"`
+	WouldUpdate int       `json:"would_update"`
+	APIRequests int       `json:"api_requests"`
+	Interrupted bool      `json:"interrupted"`
+	DurationMS  int64     `json:"duration_ms"`
+}
+
+// EventWriter writes -output=json events, one JSON object per line. A nil EventWriter writes nothing,
+// so callers don't need to check the output mode.
+type EventWriter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	started time.Time
+}
+
+// newEventWriter returns the event writer for an output mode, nil for text. With json the events
+// are written to stdout and os.Stdout is pointed at stderr, so all human-readable output, including
+// git's, goes to stderr and stdout only contains events.
+func newEventWriter(output string) *EventWriter {
+	if output != outputJSON {
+		return nil
+	}
+	events := &EventWriter{encoder: json.NewEncoder(os.Stdout), started: time.Now()}
+	os.Stdout = os.Stderr
+	return events
+}
+
+// validateOutput checks the value of -output
+func validateOutput(output string) error {
+	switch output {
+	case outputText, outputJSON:
+		return nil
+	}
+	return fmt.Errorf("invalid output '%s'. Must be '%s' or '%s'", output, outputText, outputJSON)
+}
+
+// emit writes a single event
+func (e *EventWriter) emit(event any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.encoder.Encode(event); err != nil {
+		log.Printf("Warning: Failed to write event: %v", err)
+	}
+}
+
+// repoEvent returns the shared fields of an event about a repository
+func repoEvent(event string, rec *RepoRecord) RepoEvent {
+	return RepoEvent{
+		Event:     event,
+		Time:      time.Now().UTC(),
+		Platform:  rec.Platform,
+		Namespace: rec.Namespace,
+		Repo:      rec.Name,
+	}
+}
+
+// reposDiscovered emits repo_discovered for each listed repository
+func (e *EventWriter) reposDiscovered(config Config, records []*RepoRecord) {
+	if e == nil {
+		return
+	}
+	for _, rec := range records {
+		e.emit(RepoDiscoveredEvent{
+			RepoEvent:     repoEvent(eventRepoDiscovered, rec),
+			ID:            rec.ID,
+			WebURL:        rec.WebURL,
+			CloneURL:      rec.CloneURL(config.UseSSH),
+			DefaultBranch: rec.DefaultBranch,
+			Visibility:    rec.Visibility,
+			Archived:      rec.Archived,
+			LocalPath:     rec.LocalPath,
+		})
+	}
+}
+
+// catalogsChecked emits catalog_checked for the candidates of the --prod filter whose catalog was
+// checked. Candidates the filter didn't get to because the run was interrupted are left out.
+func (e *EventWriter) catalogsChecked(candidates, production []*RepoRecord) {
+	if e == nil {
+		return
+	}
+	selected := make(map[*RepoRecord]bool, len(production))
+	for _, rec := range production {
+		selected[rec] = true
+	}
+	for _, rec := range candidates {
+		if !selected[rec] && rec.Outcome != outcomeSkipped {
+			continue
+		}
+		event := CatalogCheckedEvent{
+			RepoEvent:  repoEvent(eventCatalogChecked, rec),
+			HasCatalog: rec.Catalog != nil,
+			Production: selected[rec],
+			Error:      rec.CatalogError,
+		}
+		if rec.Catalog != nil {
+			event.Type = rec.Catalog.Type
+			event.Lifecycle = rec.Catalog.Component.Lifecycle
+		}
+		e.emit(event)
+	}
+}
+
+// cloneStarted emits clone_started
+func (e *EventWriter) cloneStarted(config Config, rec *RepoRecord) {
+	if e == nil {
+		return
+	}
+	e.emit(CloneStartedEvent{
+		RepoEvent: repoEvent(eventCloneStarted, rec),
+		CloneURL:  rec.CloneURL(config.UseSSH),
+		LocalPath: rec.LocalPath,
+	})
+}
+
+// cloneDone emits clone_finished or clone_failed depending on the outcome stored on the record
+func (e *EventWriter) cloneDone(rec *RepoRecord, duration time.Duration) {
+	if e == nil {
+		return
+	}
+	if rec.Outcome == outcomeFailed || rec.Outcome == outcomeCanceled {
+		e.emit(CloneFailedEvent{
+			RepoEvent:  repoEvent(eventCloneFailed, rec),
+			LocalPath:  rec.LocalPath,
+			Outcome:    rec.Outcome,
+			Error:      rec.Reason,
+			DurationMS: duration.Milliseconds(),
+		})
+		return
+	}
+	e.emit(CloneFinishedEvent{
+		RepoEvent:  repoEvent(eventCloneFinished, rec),
+		LocalPath:  rec.LocalPath,
+		Outcome:    rec.Outcome,
+		DurationMS: duration.Milliseconds(),
+	})
+}
+
+// summary emits the summary event of the run
+func (e *EventWriter) summary(config Config, records []*RepoRecord, interrupted bool) {
+	if e == nil {
+		return
+	}
+	e.emit(SummaryEvent{
+		Event:       eventSummary,
+		Time:        time.Now().UTC(),
+		Discovered:  len(records),
+		Cloned:      countOutcome(records, outcomeCloned),
+		Updated:     countOutcome(records, outcomeUpdated),
+		Exists:      countOutcome(records, outcomeExists),
+		Skipped:     countOutcome(records, outcomeSkipped),
+		Failed:      countOutcome(records, outcomeFailed),
+		Canceled:    countOutcome(records, outcomeCanceled),
+		NotStarted:  countOutcome(records, outcomeNotStarted),
+		WouldClone:  countOutcome(records, outcomePlannedClone),
+		WouldUpdate: countOutcome(records, outcomePlannedUpdate),
+		APIRequests: config.Rate.Calls(),
+		Interrupted: interrupted,
+		DurationMS:  time.Since(e.started).Milliseconds(),
+	})
+}
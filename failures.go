@@ -0,0 +1,256 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit codes of sync, in addition to exitOK and exitError (invalid flags and configuration errors)
+const (
+	exitPartialFailure = 3 // Some repositories failed to clone or update
+	exitTotalFailure   = 4 // Nothing could be synced: listing failed or every clone failed
+	exitInterrupted    = 5 // Run was interrupted or hit -total-timeout
+)
+
+// failuresFileName is the default name of the failures file, written to the target directory
+const failuresFileName = ".git-repo-downloader-failures.json"
+
+// FailureReport lists the repositories that failed in the last run, written to the failures file
+type FailureReport struct {
+	GeneratedAt  time.Time      `json:"generated_at"`
+	Platform     string         `json:"platform"`
+	Organization string         `json:"organization,omitempty"`
+	Failures     []FailureEntry `json:"failures"`
+}
+
+// FailureEntry is a repository that failed to clone or update
+type FailureEntry struct {
+	Platform      string `json:"platform"`
+	ID            int64  `json:"id"`
+	Namespace     string `json:"namespace"`
+	Name          string `json:"name"`
+	WebURL        string `json:"web_url"`
+	HTTPSURL      string `json:"https_url"`
+	SSHURL        string `json:"ssh_url"`
+	DefaultBranch string `json:"default_branch"`
+	LocalPath     string `json:"local_path"`
+	Error         string `json:"error"`
+}
+
+// failuresPath returns the failures file of a run, by default in the target directory
+func failuresPath(config Config) string {
+	if config.FailuresPath != "" {
+		return config.FailuresPath
+	}
+	return filepath.Join(config.TargetDir, failuresFileName)
+}
+
+// failedRecords returns the records whose clone or update failed
+func failedRecords(records []*RepoRecord) []*RepoRecord {
+	var failed []*RepoRecord
+	for _, rec := range records {
+		if rec.Outcome == outcomeFailed {
+			failed = append(failed, rec)
+		}
+	}
+	return failed
+}
+
+// writeFailures writes the failed repositories of a run to the failures file and returns how many
+// it lists. A complete run without failures removes the file, so it always describes the last run.
+// An interrupted run didn't process every repository: the failures of the previous run are kept,
+// except for the repositories this run processed, so they can still be retried.
+func writeFailures(config Config, records []*RepoRecord, interrupted bool) (int, error) {
+	path := failuresPath(config)
+
+	var failures []FailureEntry
+	if interrupted {
+		previous, _, err := readFailures(path)
+		if err != nil {
+			return 0, err
+		}
+		if previous.Platform == config.Platform && previous.Organization == config.Organization {
+			synced := make(map[stateKey]bool)
+			for _, rec := range records {
+				switch rec.Outcome {
+				case outcomeCloned, outcomeUpdated, outcomeExists, outcomeFailed:
+					synced[stateKey{rec.Platform, rec.ID}] = true
+				}
+			}
+			for _, entry := range previous.Failures {
+				if !synced[stateKey{entry.Platform, entry.ID}] {
+					failures = append(failures, entry)
+				}
+			}
+		}
+	}
+	for _, rec := range failedRecords(records) {
+		failures = append(failures, FailureEntry{
+			Platform:      rec.Platform,
+			ID:            rec.ID,
+			Namespace:     rec.Namespace,
+			Name:          rec.Name,
+			WebURL:        rec.WebURL,
+			HTTPSURL:      rec.HTTPSURL,
+			SSHURL:        rec.SSHURL,
+			DefaultBranch: rec.DefaultBranch,
+			LocalPath:     rec.LocalPath,
+			Error:         rec.Reason,
+		})
+	}
+
+	if len(failures) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return 0, fmt.Errorf("failed to remove failures file: %w", err)
+		}
+		return 0, nil
+	}
+
+	report := FailureReport{
+		GeneratedAt:  time.Now().UTC(),
+		Platform:     config.Platform,
+		Organization: config.Organization,
+		Failures:     failures,
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode failures: %w", err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write failures file: %w", err)
+	}
+	return len(failures), nil
+}
+
+// readFailures reads the failures file of the last run. found is false when the file doesn't exist,
+// which means the last run had no failures.
+func readFailures(path string) (report FailureReport, found bool, err error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return report, false, nil
+	}
+	if err != nil {
+		return report, false, fmt.Errorf("failed to read failures file: %w", err)
+	}
+	if err := json.Unmarshal(content, &report); err != nil {
+		return report, false, fmt.Errorf("failed to parse failures file %s: %w", path, err)
+	}
+	return report, true, nil
+}
+
+// retryFailedRepos clones again the repositories listed in the failures file of the last run,
+// without listing repositories or checking catalogs
+func retryFailedRepos(ctx context.Context, config Config) ([]*RepoRecord, error) {
+	path := failuresPath(config)
+	report, found, err := readFailures(path)
+	if err != nil {
+		return nil, err
+	}
+	if !found || len(report.Failures) == 0 {
+		fmt.Printf("✅ No failed repositories to retry (%s doesn't exist)\n", path)
+		return nil, nil
+	}
+	if report.Platform != config.Platform || report.Organization != config.Organization {
+		return nil, fmt.Errorf("%s lists failures of %s %s, not of %s %s",
+			path, report.Platform, orDash(report.Organization), config.Platform, orDash(config.Organization))
+	}
+
+	var records []*RepoRecord
+	for _, entry := range report.Failures {
+		records = append(records, &RepoRecord{
+			Platform:      entry.Platform,
+			ID:            entry.ID,
+			Namespace:     entry.Namespace,
+			Name:          entry.Name,
+			WebURL:        entry.WebURL,
+			HTTPSURL:      entry.HTTPSURL,
+			SSHURL:        entry.SSHURL,
+			DefaultBranch: entry.DefaultBranch,
+			LocalPath:     localRepoPath(config, entry.Name),
+		})
+	}
+
+	fmt.Printf("🔁 Retrying %d repositories that failed in the run of %s (from %s)\n\n",
+		len(records), report.GeneratedAt.Local().Format(time.RFC1123), path)
+
+	config.Progress.start(len(records))
+	defer config.Progress.stop()
+	for i, rec := range records {
+		if ctx.Err() != nil {
+			break
+		}
+		if config.Progress == nil {
+			fmt.Printf("[%d/%d] Processing: %s\n", i+1, len(records), rec.Name)
+		}
+
+		if err := cloneRecord(ctx, config, rec); err != nil {
+			log.Printf("Warning: Failed to clone %s: %v", rec.Name, err)
+			continue
+		}
+
+		fmt.Printf("✓ Successfully %s: %s\n\n", outcomeVerb(rec.Outcome), rec.Name)
+	}
+	return records, nil
+}
+
+// displayFailures prints a table of the repositories that failed to clone or update
+func displayFailures(records []*RepoRecord) {
+	failed := failedRecords(records)
+	if len(failed) == 0 {
+		return
+	}
+
+	fmt.Printf("\n❌ Failed repositories (%d):\n", len(failed))
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "   REPOSITORY\tLOCAL PATH\tERROR\n")
+	for _, rec := range failed {
+		// git errors can span several lines, the table shows the first one
+		message, _, _ := strings.Cut(rec.Reason, "\n")
+		fmt.Fprintf(table, "   %s\t%s\t%s\n", rec.Name, rec.LocalPath, message)
+	}
+	table.Flush()
+}
+
+// groupListingError reports the GitLab groups whose projects couldn't be listed with -all-groups.
+// The repositories of the other groups were still synced.
+type groupListingError struct {
+	Groups []string // Names of the groups that failed
+	Errors []error  // Listing error of each group
+}
+
+func (e *groupListingError) Error() string {
+	return fmt.Sprintf("failed to list the projects of %d groups", len(e.Groups))
+}
+
+// displayGroupFailures prints the groups whose projects couldn't be listed
+func displayGroupFailures(groupErr *groupListingError) {
+	if groupErr == nil {
+		return
+	}
+	fmt.Printf("\n❌ Groups whose projects couldn't be listed (%d):\n", len(groupErr.Groups))
+	for i, name := range groupErr.Groups {
+		fmt.Printf("   - %s: %v\n", name, groupErr.Errors[i])
+	}
+}
+
+// syncExitCode returns the exit code of a complete sync run that listed the given records.
+// listingFailures is the number of groups whose repositories couldn't be listed.
+func syncExitCode(records []*RepoRecord, listingFailures int) int {
+	failed := countOutcome(records, outcomeFailed)
+	if failed == 0 && listingFailures == 0 {
+		return exitOK
+	}
+	succeeded := countOutcome(records, outcomeCloned) + countOutcome(records, outcomeUpdated) + countOutcome(records, outcomeExists)
+	if succeeded == 0 && failed > 0 {
+		return exitTotalFailure
+	}
+	return exitPartialFailure
+}
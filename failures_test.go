@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// recordsWithOutcomes returns one record per outcome
+func recordsWithOutcomes(outcomes ...string) []*RepoRecord {
+	var records []*RepoRecord
+	for _, outcome := range outcomes {
+		records = append(records, &RepoRecord{Outcome: outcome})
+	}
+	return records
+}
+
+func TestSyncExitCode(t *testing.T) {
+	tests := []struct {
+		name            string
+		outcomes        []string
+		listingFailures int
+		want            int
+	}{
+		{name: "nothing to sync", want: exitOK},
+		{name: "all synced", outcomes: []string{outcomeCloned, outcomeUpdated, outcomeExists}, want: exitOK},
+		{name: "skipped repositories", outcomes: []string{outcomeCloned, outcomeSkipped}, want: exitOK},
+		{name: "dry run", outcomes: []string{outcomePlannedClone, outcomePlannedUpdate, outcomeExists}, want: exitOK},
+		{name: "some failed", outcomes: []string{outcomeCloned, outcomeFailed}, want: exitPartialFailure},
+		{name: "failed next to existing", outcomes: []string{outcomeExists, outcomeFailed}, want: exitPartialFailure},
+		{name: "all failed", outcomes: []string{outcomeFailed, outcomeFailed}, want: exitTotalFailure},
+		{name: "all failed, others skipped", outcomes: []string{outcomeFailed, outcomeSkipped}, want: exitTotalFailure},
+		{name: "group not listed", outcomes: []string{outcomeCloned}, listingFailures: 1, want: exitPartialFailure},
+		{name: "group not listed, other group empty", listingFailures: 1, want: exitPartialFailure},
+		{name: "group not listed, all clones failed", outcomes: []string{outcomeFailed}, listingFailures: 1, want: exitTotalFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := syncExitCode(recordsWithOutcomes(tt.outcomes...), tt.listingFailures); got != tt.want {
+				t.Errorf("syncExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFailuresMerge(t *testing.T) {
+	previous := FailureReport{
+		Platform:     "github",
+		Organization: "acme",
+		Failures: []FailureEntry{
+			{Platform: "github", ID: 1, Name: "api", Error: "old"},
+			{Platform: "github", ID: 2, Name: "billing", Error: "old"},
+			{Platform: "github", ID: 3, Name: "web", Error: "old"},
+		},
+	}
+	records := []*RepoRecord{
+		{Platform: "github", ID: 1, Name: "api", Outcome: outcomeCloned},
+		{Platform: "github", ID: 2, Name: "billing", Outcome: outcomeNotStarted},
+		{Platform: "github", ID: 3, Name: "web", Outcome: outcomeFailed, Reason: "new"},
+		{Platform: "github", ID: 4, Name: "docs", Outcome: outcomeFailed, Reason: "new"},
+		{Platform: "github", ID: 5, Name: "infra", Outcome: outcomeCanceled},
+	}
+
+	tests := []struct {
+		name         string
+		organization string
+		records      []*RepoRecord
+		interrupted  bool
+		want         string
+	}{
+		{name: "completed run replaces the file", organization: "acme", records: records, want: "docs:new,web:new"},
+		{name: "interrupted run keeps failures it didn't get to", organization: "acme", records: records, interrupted: true, want: "billing:old,docs:new,web:new"},
+		{name: "interrupted run of another organization", organization: "other", records: records, interrupted: true, want: "docs:new,web:new"},
+		{name: "interrupted run without failures", organization: "acme", records: records[:1], interrupted: true, want: "billing:old,web:old"},
+		{name: "completed run without failures removes the file", organization: "acme", records: records[:1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{Platform: "github", Organization: tt.organization, TargetDir: t.TempDir()}
+			content, err := json.Marshal(previous)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(config.TargetDir, failuresFileName), content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			written, err := writeFailures(config, tt.records, tt.interrupted)
+			if err != nil {
+				t.Fatalf("writeFailures() error = %v", err)
+			}
+			report, found, err := readFailures(failuresPath(config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.want == "" {
+				if found || written != 0 {
+					t.Errorf("writeFailures() = %d, file kept = %v, want the file removed", written, found)
+				}
+				return
+			}
+
+			var got []string
+			for _, entry := range report.Failures {
+				got = append(got, entry.Name+":"+entry.Error)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != tt.want || written != len(got) {
+				t.Errorf("writeFailures() = %d, failures %s, want %s", written, strings.Join(got, ","), tt.want)
+			}
+			if report.Organization != tt.organization {
+				t.Errorf("Organization = %s, want %s", report.Organization, tt.organization)
+			}
+		})
+	}
+}
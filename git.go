@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// gitKillDelay is how long a canceled git command may take to close its output before it is abandoned
+const gitKillDelay = 5 * time.Second
+
+// gitOutput runs a git command in dir and returns its trimmed stdout, including stderr in the error
+func gitOutput(dir string, env []string, args ...string) (string, error) {
+	return gitOutputContext(context.Background(), dir, env, args...)
+}
+
+// gitOutputContext is gitOutput with a context that kills git when it is canceled
+func gitOutputContext(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.WaitDelay = gitKillDelay
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			return "", fmt.Errorf("git %s failed: %w", args[0], err)
+		}
+		return "", fmt.Errorf("git %s failed: %s", args[0], message)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// gitStderrTail is how much of the standard error of streamed git commands is kept for GitError
+const gitStderrTail = 4096
+
+// GitError is a failed git command together with the end of what it printed to standard error,
+// which is used to tell network problems from permanent failures
+type GitError struct {
+	Err    error
+	Stderr string
+}
+
+func (e *GitError) Error() string {
+	if message := e.message(); message != "" {
+		return e.Err.Error() + ": " + message
+	}
+	return e.Err.Error()
+}
+
+// message returns the first error git printed, e.g. "fatal: repository 'https://…' not found".
+// Later lines are usually generic, like "fatal: Could not read from remote repository."
+func (e *GitError) message() string {
+	for _, line := range strings.FieldsFunc(e.Stderr, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "fatal:") || strings.HasPrefix(line, "error:") {
+			return line
+		}
+	}
+	return ""
+}
+
+func (e *GitError) Unwrap() error {
+	return e.Err
+}
+
+// tailWriter keeps the last gitStderrTail bytes written to it
+type tailWriter struct {
+	buf []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > gitStderrTail {
+		w.buf = w.buf[len(w.buf)-gitStderrTail:]
+	}
+	return len(p), nil
+}
+
+// runGit runs a git command in dir, streaming its output to the terminal or the live progress view. git is killed when ctx is canceled.
+// Failures are returned as *GitError.
+func runGit(ctx context.Context, dir string, env []string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.WaitDelay = gitKillDelay
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	stderr := &tailWriter{}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = io.MultiWriter(gitProgress(ctx, os.Stderr), stderr)
+	if err := cmd.Run(); err != nil {
+		return &GitError{Err: err, Stderr: string(stderr.buf)}
+	}
+	return nil
+}
+
+// parseRemoteURL splits an HTTPS, ssh:// or scp-style git remote URL into host and repository path
+// (without the .git suffix), e.g. git@github.com:acme/api.git gives github.com and acme/api.
+func parseRemoteURL(remote string) (string, string, error) {
+	remote = strings.TrimSpace(remote)
+
+	var host, path string
+	if i := strings.Index(remote, "://"); i >= 0 {
+		rest := remote[i+3:]
+		slash := strings.Index(rest, "/")
+		if slash < 0 {
+			return "", "", fmt.Errorf("invalid remote URL: %s", remote)
+		}
+		host, path = rest[:slash], rest[slash+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		if colon := strings.Index(host, ":"); colon >= 0 {
+			host = host[:colon]
+		}
+	} else if colon := strings.Index(remote, ":"); colon >= 0 {
+		host, path = remote[:colon], remote[colon+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	} else {
+		return "", "", fmt.Errorf("invalid remote URL: %s", remote)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if host == "" || path == "" {
+		return "", "", fmt.Errorf("invalid remote URL: %s", remote)
+	}
+	return host, path, nil
+}
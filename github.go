@@ -5,63 +5,53 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"net/http"
 
 	"github.com/google/go-github/v66/github"
 	"golang.org/x/oauth2"
-	"gopkg.in/yaml.v3"
 )
 
-func downloadGitHubRepos(config Config) error {
-	ctx := context.Background()
-
-	// Create GitHub client
-	var client *github.Client
-	if config.Token != "" {
-		ts := oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: config.Token},
-		)
-		tc := oauth2.NewClient(ctx, ts)
-		client = github.NewClient(tc)
-	} else {
-		client = github.NewClient(nil)
+func downloadGitHubRepos(ctx context.Context, config Config) ([]*RepoRecord, error) {
+	if config.Token == "" {
 		fmt.Println("Warning: No token provided. Only public repositories will be accessible.")
 	}
 
-	// List all repositories for the organization
-	fmt.Printf("Fetching repositories for GitHub organization: %s\n", config.Organization)
-	
-	var allRepos []*github.Repository
-	opt := &github.RepositoryListByOrgOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
+	// List all repositories for the organization. GraphQL also fetches every .catalog.yml in the same requests.
+	httpClient := newAPIHTTPClient(config)
+	client := newGitHubClient(ctx, config.Token, httpClient)
+	graphQL := useGitHubGraphQL(config)
+	var records []*RepoRecord
+	var err error
+	if graphQL {
+		fmt.Printf("Fetching repositories and catalogs for GitHub organization: %s (GraphQL)\n", config.Organization)
+		records, err = listGitHubReposGraphQL(ctx, httpClient, config)
+	} else {
+		fmt.Printf("Fetching repositories for GitHub organization: %s\n", config.Organization)
+		records, err = listGitHubReposREST(ctx, client, config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing repositories: %w", err)
 	}
 
-	for {
-		repos, resp, err := client.Repositories.ListByOrg(ctx, config.Organization, opt)
-		if err != nil {
-			return fmt.Errorf("error listing repositories: %w", err)
-		}
-
-		allRepos = append(allRepos, repos...)
+	fmt.Printf("Found %d repositories\n", len(records))
+	config.Events.reposDiscovered(config, records)
 
-		if resp.NextPage == 0 {
-			break
-		}
-		opt.Page = resp.NextPage
+	reposToDownload := records
+	if config.SkipArchived {
+		reposToDownload = skipArchivedRecords(records)
 	}
 
-	fmt.Printf("Found %d repositories\n", len(allRepos))
-
 	// If production mode is enabled, filter repositories
-	var reposToDownload []*github.Repository
 	if config.ProdMode {
 		fmt.Printf("🔍 Production mode enabled: Checking .catalog.yml files for lifecycle: production\n")
-		reposToDownload = filterProductionRepos(ctx, client, allRepos, config.Organization)
+		candidates := reposToDownload
+		if graphQL {
+			reposToDownload = selectProductionRecords(candidates)
+		} else {
+			reposToDownload = filterProductionRepos(ctx, client, candidates, config.Organization, config.Retry)
+		}
+		config.Events.catalogsChecked(candidates, reposToDownload)
 		fmt.Printf("📋 Found %d repositories with lifecycle: production\n", len(reposToDownload))
-	} else {
-		reposToDownload = allRepos
 	}
 
 	if len(reposToDownload) == 0 {
@@ -70,108 +60,175 @@ func downloadGitHubRepos(config Config) error {
 		} else {
 			fmt.Printf("⚠️  No repositories to download\n")
 		}
-		return nil
+		return records, nil
+	}
+
+	if config.ListOnly {
+		return records, nil
+	}
+	if config.DryRun {
+		for _, rec := range reposToDownload {
+			planRecord(config, rec)
+		}
+		return records, nil
 	}
 
 	fmt.Printf("\n")
 
 	// Download each repository
-	for i, repo := range reposToDownload {
-		fmt.Printf("[%d/%d] Processing: %s\n", i+1, len(reposToDownload), repo.GetName())
+	config.Progress.start(len(reposToDownload))
+	defer config.Progress.stop()
+	for i, rec := range reposToDownload {
+		if ctx.Err() != nil {
+			break
+		}
+		if config.Progress == nil {
+			fmt.Printf("[%d/%d] Processing: %s\n", i+1, len(reposToDownload), rec.Name)
+		}
 		
-		if err := cloneRepository(repo.GetName(), getGitHubCloneURL(repo, config.UseSSH), config.TargetDir); err != nil {
-			log.Printf("Warning: Failed to clone %s: %v", repo.GetName(), err)
+		if err := cloneRecord(ctx, config, rec); err != nil {
+			log.Printf("Warning: Failed to clone %s: %v", rec.Name, err)
 			continue
 		}
 		
-		fmt.Printf("✓ Successfully cloned: %s\n\n", repo.GetName())
+		fmt.Printf("✓ Successfully %s: %s\n\n", outcomeVerb(rec.Outcome), rec.Name)
+	}
+
+	return records, nil
+}
+
+// listGitHubReposREST lists the repositories of the organization with the REST API, 100 per request
+func listGitHubReposREST(ctx context.Context, client *github.Client, config Config) ([]*RepoRecord, error) {
+	var records []*RepoRecord
+	opt := &github.RepositoryListByOrgOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
 	}
 
-	return nil
+	for {
+		var repos []*github.Repository
+		var resp *github.Response
+		err := retryAPI(ctx, config.Retry, "listing repositories", func() (err error) {
+			repos, resp, err = client.Repositories.ListByOrg(ctx, config.Organization, opt)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range repos {
+			records = append(records, newGitHubRecord(repo, config))
+		}
+
+		if resp.NextPage == 0 {
+			return records, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// newGitHubClient creates a GitHub client, authenticated when a token is provided.
+// httpClient is used for the requests unless it is nil.
+func newGitHubClient(ctx context.Context, token string, httpClient *http.Client) *github.Client {
+	if token == "" {
+		return github.NewClient(httpClient)
+	}
+	if httpClient != nil {
+		// oauth2 builds on the HTTP client found in the context
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, httpClient)
+	}
+
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	tc := oauth2.NewClient(ctx, ts)
+	return github.NewClient(tc)
 }
 
 // filterProductionRepos checks each repository for .catalog.yml with lifecycle: production
-func filterProductionRepos(ctx context.Context, client *github.Client, repos []*github.Repository, org string) []*github.Repository {
-	var productionRepos []*github.Repository
+// Repositories that are filtered out are marked as skipped.
+func filterProductionRepos(ctx context.Context, client *github.Client, repos []*RepoRecord, org string, retry RetryPolicy) []*RepoRecord {
+	var productionRepos []*RepoRecord
 
-	for i, repo := range repos {
-		fmt.Printf("[%d/%d] Checking %s for .catalog.yml...", i+1, len(repos), repo.GetName())
+	for i, rec := range repos {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("[%d/%d] Checking %s for .catalog.yml...", i+1, len(repos), rec.Name)
 		
-		isProduction, err := checkGitHubCatalogFile(ctx, client, org, repo.GetName())
+		catalog, err := checkGitHubCatalogFile(ctx, client, retry, org, rec.Name)
 		if err != nil {
 			fmt.Printf(" ❌ Error: %v\n", err)
+			rec.Outcome = outcomeSkipped
+			rec.Reason = "catalog check failed"
+			rec.CatalogError = err.Error()
 			continue
 		}
+		rec.Catalog = catalog
 
-		if isProduction {
+		if isProductionCatalog(catalog) {
 			fmt.Printf(" ✅ Production lifecycle found\n")
-			productionRepos = append(productionRepos, repo)
+			productionRepos = append(productionRepos, rec)
 		} else {
 			fmt.Printf(" ⏭️  Not production or no .catalog.yml\n")
+			rec.Outcome = outcomeSkipped
+			rec.Reason = "not production"
 		}
 	}
 
 	return productionRepos
 }
 
-// checkGitHubCatalogFile fetches and parses .catalog.yml to check for lifecycle: production
-func checkGitHubCatalogFile(ctx context.Context, client *github.Client, owner, repo string) (bool, error) {
+// checkGitHubCatalogFile fetches and parses .catalog.yml, returning nil if the repository has none
+func checkGitHubCatalogFile(ctx context.Context, client *github.Client, retry RetryPolicy, owner, repo string) (*CatalogYAML, error) {
 	// Try to get .catalog.yml file from the repository
-	fileContent, _, resp, err := client.Repositories.GetContents(ctx, owner, repo, ".catalog.yml", nil)
+	var fileContent *github.RepositoryContent
+	var resp *github.Response
+	err := retryAPI(ctx, retry, "fetching .catalog.yml of "+repo, func() (err error) {
+		fileContent, _, resp, err = client.Repositories.GetContents(ctx, owner, repo, ".catalog.yml", nil)
+		return err
+	})
 	if err != nil {
 		if resp != nil && resp.StatusCode == 404 {
-			return false, nil // File not found, not an error
+			return nil, nil // File not found, not an error
 		}
-		return false, fmt.Errorf("failed to fetch .catalog.yml: %w", err)
+		return nil, fmt.Errorf("failed to fetch .catalog.yml: %w", err)
 	}
 
 	if fileContent == nil {
-		return false, nil // File not found
+		return nil, nil // File not found
 	}
 
 	// Decode base64 content
 	content, err := base64.StdEncoding.DecodeString(*fileContent.Content)
 	if err != nil {
-		return false, fmt.Errorf("failed to decode file content: %w", err)
+		return nil, fmt.Errorf("failed to decode file content: %w", err)
 	}
 
 	// Parse YAML
-	var catalog CatalogYAML
-	if err := yaml.Unmarshal(content, &catalog); err != nil {
-		return false, fmt.Errorf("failed to parse YAML: %w", err)
-	}
-
-	// Check if lifecycle is production
-	return catalog.Component.Lifecycle == "production", nil
+	return parseCatalog(content)
 }
 
-func getGitHubCloneURL(repo *github.Repository, useSSH bool) string {
-	if useSSH {
-		return repo.GetSSHURL()
+// newGitHubRecord converts a GitHub repository into a platform-neutral record
+func newGitHubRecord(repo *github.Repository, config Config) *RepoRecord {
+	visibility := repo.GetVisibility()
+	if visibility == "" {
+		visibility = "public"
+		if repo.GetPrivate() {
+			visibility = "private"
+		}
+	}
+
+	return &RepoRecord{
+		Platform:      "github",
+		ID:            repo.GetID(),
+		Namespace:     repo.GetOwner().GetLogin(),
+		Name:          repo.GetName(),
+		WebURL:        repo.GetHTMLURL(),
+		HTTPSURL:      repo.GetCloneURL(),
+		SSHURL:        repo.GetSSHURL(),
+		DefaultBranch: repo.GetDefaultBranch(),
+		Visibility:    visibility,
+		Archived:      repo.GetArchived(),
+		LocalPath:     localRepoPath(config, repo.GetName()),
 	}
-	return repo.GetCloneURL()
 }
-
-func cloneRepository(repoName, cloneURL, targetDir string) error {
-	repoPath := filepath.Join(targetDir, repoName)
-	
-	// Check if repository already exists
-	if _, err := os.Stat(repoPath); err == nil {
-		fmt.Printf("  Repository already exists at %s, skipping...\n", repoPath)
-		return nil
-	}
-
-	// Clone the repository
-	fmt.Printf("  Cloning from: %s\n", cloneURL)
-	fmt.Printf("  Target path: %s\n", repoPath)
-	
-	cmd := exec.Command("git", "clone", cloneURL, repoPath)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("git clone failed: %w", err)
-	}
-	
-	return nil
-} 
\ No newline at end of file
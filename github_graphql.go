@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// githubGraphQLEndpoint is the GitHub GraphQL API
+const githubGraphQLEndpoint = "https://api.github.com/graphql"
+
+// githubReposQuery pages through the repositories of an organization and fetches the .catalog.yml
+// committed on the default branch in the same query
+const githubReposQuery = `query($org: String!, $cursor: String) {
+  organization(login: $org) {
+    repositories(first: 100, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        databaseId
+        name
+        url
+        sshUrl
+        visibility
+        isArchived
+        owner { login }
+        defaultBranchRef { name }
+        catalog: object(expression: "HEAD:.catalog.yml") {
+          ... on Blob { text isBinary }
+        }
+      }
+    }
+  }
+}`
+
+// githubRepoNode is a repository returned by githubReposQuery
+type githubRepoNode struct {
+	DatabaseID int64  `json:"databaseId"`
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	SSHURL     string `json:"sshUrl"`
+	Visibility string `json:"visibility"`
+	IsArchived bool   `json:"isArchived"`
+	Owner      struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	DefaultBranchRef *struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+	Catalog *struct {
+		Text     *string `json:"text"`
+		IsBinary bool    `json:"isBinary"`
+	} `json:"catalog"`
+}
+
+// useGitHubGraphQL reports whether repositories are listed with GraphQL, which GitHub only allows with a token
+func useGitHubGraphQL(config Config) bool {
+	return config.API == apiGraphQL || (config.API == apiAuto && config.Token != "")
+}
+
+// listGitHubReposGraphQL lists the repositories of the organization together with their catalogs,
+// 100 repositories per request. Catalogs that fail to parse are recorded on the record.
+func listGitHubReposGraphQL(ctx context.Context, httpClient *http.Client, config Config) ([]*RepoRecord, error) {
+	header := http.Header{}
+	header.Set("Authorization", "bearer "+config.Token)
+
+	var records []*RepoRecord
+	variables := map[string]interface{}{"org": config.Organization, "cursor": nil}
+	for page := 1; ; page++ {
+		var data struct {
+			Organization *struct {
+				Repositories struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []githubRepoNode `json:"nodes"`
+				} `json:"repositories"`
+			} `json:"organization"`
+		}
+		err := retryAPI(ctx, config.Retry, fmt.Sprintf("listing repositories (page %d)", page), func() error {
+			return graphQLRequest(ctx, httpClient, githubGraphQLEndpoint, header, githubReposQuery, variables, &data)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if data.Organization == nil {
+			return nil, fmt.Errorf("organization '%s' not found", config.Organization)
+		}
+
+		for _, node := range data.Organization.Repositories.Nodes {
+			records = append(records, newGitHubGraphQLRecord(node, config))
+		}
+
+		pageInfo := data.Organization.Repositories.PageInfo
+		if !pageInfo.HasNextPage {
+			return records, nil
+		}
+		variables["cursor"] = pageInfo.EndCursor
+	}
+}
+
+// newGitHubGraphQLRecord converts a GraphQL repository node into a platform-neutral record
+func newGitHubGraphQLRecord(node githubRepoNode, config Config) *RepoRecord {
+	rec := &RepoRecord{
+		Platform:   "github",
+		ID:         node.DatabaseID,
+		Namespace:  node.Owner.Login,
+		Name:       node.Name,
+		WebURL:     node.URL,
+		HTTPSURL:   node.URL + ".git",
+		SSHURL:     node.SSHURL,
+		Visibility: strings.ToLower(node.Visibility),
+		Archived:   node.IsArchived,
+		LocalPath:  localRepoPath(config, node.Name),
+	}
+	if node.DefaultBranchRef != nil {
+		rec.DefaultBranch = node.DefaultBranchRef.Name
+	}
+
+	switch {
+	case node.Catalog == nil:
+		// No .catalog.yml (or an empty repository)
+	case node.Catalog.IsBinary || node.Catalog.Text == nil:
+		rec.CatalogError = "failed to parse YAML: .catalog.yml is not a text file"
+	default:
+		catalog, err := parseCatalog([]byte(*node.Catalog.Text))
+		if err != nil {
+			rec.CatalogError = err.Error()
+		} else {
+			rec.Catalog = catalog
+		}
+	}
+	return rec
+}
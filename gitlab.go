@@ -1,45 +1,65 @@
 package main
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
 	"log"
+	"net/http"
 	"net/url"
 
 	"github.com/xanzy/go-gitlab"
-	"gopkg.in/yaml.v3"
 )
 
-func downloadGitLabRepos(config Config) error {
+func downloadGitLabRepos(ctx context.Context, config Config) ([]*RepoRecord, error) {
 	// Create GitLab client
-	var client *gitlab.Client
-	var err error
-
-	if config.Token != "" {
-		client, err = gitlab.NewClient(config.Token, gitlab.WithBaseURL(config.GitLabURL))
-		if err != nil {
-			return fmt.Errorf("error creating GitLab client: %w", err)
-		}
-	} else {
+	httpClient := newAPIHTTPClient(config)
+	client, err := newGitLabClient(config.Token, config.GitLabURL, httpClient)
+	if err != nil {
+		return nil, err
+	}
+	if config.Token == "" {
 		// For public repositories, we can still try without authentication
-		client, err = gitlab.NewClient("", gitlab.WithBaseURL(config.GitLabURL))
-		if err != nil {
-			return fmt.Errorf("error creating GitLab client: %w", err)
-		}
 		fmt.Println("Warning: No token provided. Only public repositories will be accessible.")
 	}
 
+	// Older instances can't fetch catalogs with GraphQL, fall back to REST for them
+	if config.API != apiREST {
+		if err := checkGitLabGraphQL(ctx, httpClient, config); err != nil {
+			if config.API == apiGraphQL {
+				return nil, fmt.Errorf("GraphQL API unavailable: %w", err)
+			}
+			fmt.Printf("GraphQL API unavailable (%v), using the REST API\n", err)
+			config.API = apiREST
+		}
+	}
+
 	if config.AllGroups {
 		// Download from all groups
-		return downloadFromAllGroups(client, config)
+		return downloadFromAllGroups(ctx, client, config)
 	} else {
 		// Download from specific group
-		return downloadFromSpecificGroup(client, config)
+		return downloadFromSpecificGroup(ctx, client, config)
+	}
+}
+
+// newGitLabClient creates a GitLab client for the given instance; an empty token gives anonymous access.
+// httpClient is used for the requests unless it is nil.
+func newGitLabClient(token, gitlabURL string, httpClient *http.Client) (*gitlab.Client, error) {
+	// The client's built-in retries are disabled, API calls are retried by retryAPI following -retries
+	options := []gitlab.ClientOptionFunc{gitlab.WithBaseURL(gitlabURL), gitlab.WithCustomRetryMax(0)}
+	if httpClient != nil {
+		options = append(options, gitlab.WithHTTPClient(httpClient))
+	}
+	client, err := gitlab.NewClient(token, options...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GitLab client: %w", err)
 	}
+	return client, nil
 }
 
 // downloadFromAllGroups discovers all groups and downloads repositories from each
-func downloadFromAllGroups(client *gitlab.Client, config Config) error {
+func downloadFromAllGroups(ctx context.Context, client *gitlab.Client, config Config) ([]*RepoRecord, error) {
 	fmt.Printf("🔍 Discovering all groups you have access to...\n")
 
 	// List all groups the user has access to
@@ -53,9 +73,14 @@ func downloadFromAllGroups(client *gitlab.Client, config Config) error {
 	}
 
 	for {
-		groups, resp, err := client.Groups.ListGroups(opt)
+		var groups []*gitlab.Group
+		var resp *gitlab.Response
+		err := retryAPI(ctx, config.Retry, "listing groups", func() (err error) {
+			groups, resp, err = client.Groups.ListGroups(opt, gitlab.WithContext(ctx))
+			return err
+		})
 		if err != nil {
-			return fmt.Errorf("error listing groups: %w", err)
+			return nil, fmt.Errorf("error listing groups: %w", err)
 		}
 
 		allGroups = append(allGroups, groups...)
@@ -74,52 +99,86 @@ func downloadFromAllGroups(client *gitlab.Client, config Config) error {
 
 	if len(allGroups) == 0 {
 		fmt.Printf("⚠️  No groups found. You may need proper permissions or a valid token.\n")
-		return nil
+		return nil, nil
 	}
 
+	var allRecords []*RepoRecord
+	var groupErr groupListingError
 	totalReposDownloaded := 0
 	totalReposScanned := 0
 
 	// Download repositories from each group
 	for i, group := range allGroups {
+		if ctx.Err() != nil {
+			break
+		}
 		fmt.Printf("🗂️  [%d/%d] Processing group: %s\n", i+1, len(allGroups), group.Name)
 		
 		// Create a temporary config for this specific group
 		groupConfig := config
 		groupConfig.Organization = group.Path
 		
-		downloaded, scanned, err := downloadFromSpecificGroupInternal(client, groupConfig, group)
+		records, err := downloadFromSpecificGroupInternal(ctx, client, groupConfig, group)
 		if err != nil {
 			log.Printf("Warning: Failed to process group %s: %v", group.Name, err)
+			groupErr.Groups = append(groupErr.Groups, group.Name)
+			groupErr.Errors = append(groupErr.Errors, err)
 			continue
 		}
 
+		allRecords = append(allRecords, records...)
+		totalReposScanned += len(records)
+		if config.DryRun {
+			planned := countOutcome(records, outcomePlannedClone) + countOutcome(records, outcomePlannedUpdate)
+			fmt.Printf("   ✓ Group %s: %d repositories to clone or update\n\n", group.Name, planned)
+			continue
+		}
+		downloaded := countOutcome(records, outcomeCloned) + countOutcome(records, outcomeUpdated) + countOutcome(records, outcomeExists)
 		totalReposDownloaded += downloaded
-		totalReposScanned += scanned
 		fmt.Printf("   ✓ Group %s: %d repositories downloaded\n\n", group.Name, downloaded)
 	}
 
+	if ctx.Err() != nil {
+		return allRecords, nil
+	}
+
+	if len(groupErr.Groups) == len(allGroups) {
+		return nil, fmt.Errorf("failed to list the projects of all %d groups", len(allGroups))
+	}
+
 	fmt.Printf("🎉 All groups processed!\n")
 	fmt.Printf("📊 Summary:\n")
 	fmt.Printf("   - Groups processed: %d\n", len(allGroups))
+	if len(groupErr.Groups) > 0 {
+		fmt.Printf("   - Groups that couldn't be listed: %d\n", len(groupErr.Groups))
+	}
 	fmt.Printf("   - Total repositories scanned: %d\n", totalReposScanned)
-	fmt.Printf("   - Total repositories downloaded: %d\n", totalReposDownloaded)
+	if !config.DryRun {
+		fmt.Printf("   - Total repositories downloaded: %d\n", totalReposDownloaded)
+	}
 
-	return nil
+	if len(groupErr.Groups) > 0 {
+		return allRecords, &groupErr
+	}
+	return allRecords, nil
 }
 
 // downloadFromSpecificGroup downloads repositories from a single specified group
-func downloadFromSpecificGroup(client *gitlab.Client, config Config) error {
+func downloadFromSpecificGroup(ctx context.Context, client *gitlab.Client, config Config) ([]*RepoRecord, error) {
 	fmt.Printf("Fetching repositories for GitLab group: %s\n", config.Organization)
 	
 	// Search for the group
-	groups, _, err := client.Groups.SearchGroup(config.Organization)
+	var groups []*gitlab.Group
+	err := retryAPI(ctx, config.Retry, "searching for group", func() (err error) {
+		groups, _, err = client.Groups.SearchGroup(config.Organization, gitlab.WithContext(ctx))
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("error searching for group: %w", err)
+		return nil, fmt.Errorf("error searching for group: %w", err)
 	}
 
 	if len(groups) == 0 {
-		return fmt.Errorf("group '%s' not found", config.Organization)
+		return nil, fmt.Errorf("group '%s' not found", config.Organization)
 	}
 
 	// Find exact match or first match if no exact match
@@ -136,52 +195,49 @@ func downloadFromSpecificGroup(client *gitlab.Client, config Config) error {
 		fmt.Printf("Using group: %s (path: %s)\n", selectedGroup.Name, selectedGroup.Path)
 	}
 
-	_, _, err = downloadFromSpecificGroupInternal(client, config, selectedGroup)
-	return err
+	return downloadFromSpecificGroupInternal(ctx, client, config, selectedGroup)
 }
 
 // downloadFromSpecificGroupInternal handles the actual downloading logic for a group
-func downloadFromSpecificGroupInternal(client *gitlab.Client, config Config, group *gitlab.Group) (int, int, error) {
-	// List all projects in the group
-	var allProjects []*gitlab.Project
-	opt := &gitlab.ListGroupProjectsOptions{
-		ListOptions: gitlab.ListOptions{
-			PerPage: 100,
-			Page:    1,
-		},
-		IncludeSubGroups: gitlab.Bool(true), // Include subgroups
+func downloadFromSpecificGroupInternal(ctx context.Context, client *gitlab.Client, config Config, group *gitlab.Group) ([]*RepoRecord, error) {
+	// List all projects in the group. GraphQL also fetches every .catalog.yml in the same requests.
+	graphQL := config.API != apiREST
+	var records []*RepoRecord
+	var err error
+	if graphQL {
+		records, err = listGitLabProjectsGraphQL(ctx, newAPIHTTPClient(config), config, group.FullPath)
+	} else {
+		records, err = listGitLabProjectsREST(ctx, client, config, group)
 	}
-
-	for {
-		projects, resp, err := client.Groups.ListGroupProjects(group.ID, opt)
-		if err != nil {
-			return 0, 0, fmt.Errorf("error listing group projects: %w", err)
-		}
-
-		allProjects = append(allProjects, projects...)
-
-		if resp.NextPage == 0 {
-			break
-		}
-		opt.Page = resp.NextPage
+	if err != nil {
+		return nil, fmt.Errorf("error listing group projects: %w", err)
 	}
 
 	if !config.AllGroups {
-		fmt.Printf("Found %d repositories\n", len(allProjects))
+		fmt.Printf("Found %d repositories\n", len(records))
+	}
+	config.Events.reposDiscovered(config, records)
+
+	projectsToDownload := records
+	if config.SkipArchived {
+		projectsToDownload = skipArchivedRecords(records)
 	}
 
 	// If production mode is enabled, filter repositories
-	var projectsToDownload []*gitlab.Project
 	if config.ProdMode {
 		if !config.AllGroups {
 			fmt.Printf("🔍 Production mode enabled: Checking .catalog.yml files for lifecycle: production\n")
 		}
-		projectsToDownload = filterProductionProjects(client, allProjects)
+		candidates := projectsToDownload
+		if graphQL {
+			projectsToDownload = selectProductionRecords(candidates)
+		} else {
+			projectsToDownload = filterProductionProjects(ctx, client, candidates, config.Retry)
+		}
+		config.Events.catalogsChecked(candidates, projectsToDownload)
 		if !config.AllGroups {
 			fmt.Printf("📋 Found %d repositories with lifecycle: production\n", len(projectsToDownload))
 		}
-	} else {
-		projectsToDownload = allProjects
 	}
 
 	if len(projectsToDownload) == 0 {
@@ -192,105 +248,177 @@ func downloadFromSpecificGroupInternal(client *gitlab.Client, config Config, gro
 				fmt.Printf("⚠️  No repositories to download\n")
 			}
 		}
-		return 0, len(allProjects), nil
+		return records, nil
+	}
+
+	if config.ListOnly {
+		return records, nil
+	}
+	if config.DryRun {
+		for _, rec := range projectsToDownload {
+			planRecord(config, rec)
+		}
+		return records, nil
 	}
 
 	if !config.AllGroups {
 		fmt.Printf("\n")
 	}
 
-	downloadedCount := 0
-
 	// Download each repository
-	for i, project := range projectsToDownload {
-		if config.AllGroups {
-			fmt.Printf("     [%d/%d] Processing: %s\n", i+1, len(projectsToDownload), project.Name)
-		} else {
-			fmt.Printf("[%d/%d] Processing: %s\n", i+1, len(projectsToDownload), project.Name)
+	config.Progress.start(len(projectsToDownload))
+	defer config.Progress.stop()
+	for i, rec := range projectsToDownload {
+		if ctx.Err() != nil {
+			break
+		}
+		switch {
+		case config.Progress != nil:
+		case config.AllGroups:
+			fmt.Printf("     [%d/%d] Processing: %s\n", i+1, len(projectsToDownload), rec.Name)
+		default:
+			fmt.Printf("[%d/%d] Processing: %s\n", i+1, len(projectsToDownload), rec.Name)
 		}
 		
-		cloneURL := getGitLabCloneURL(project, config.UseSSH)
-		if err := cloneRepository(project.Name, cloneURL, config.TargetDir); err != nil {
-			log.Printf("Warning: Failed to clone %s: %v", project.Name, err)
+		if err := cloneRecord(ctx, config, rec); err != nil {
+			log.Printf("Warning: Failed to clone %s: %v", rec.Name, err)
 			continue
 		}
 		
 		if config.AllGroups {
-			fmt.Printf("     ✓ Successfully cloned: %s\n", project.Name)
+			fmt.Printf("     ✓ Successfully %s: %s\n", outcomeVerb(rec.Outcome), rec.Name)
 		} else {
-			fmt.Printf("✓ Successfully cloned: %s\n\n", project.Name)
+			fmt.Printf("✓ Successfully %s: %s\n\n", outcomeVerb(rec.Outcome), rec.Name)
 		}
-		downloadedCount++
 	}
 
-	return downloadedCount, len(allProjects), nil
+	return records, nil
+}
+
+// listGitLabProjectsREST lists the projects of a group and its subgroups with the REST API, 100 per request
+func listGitLabProjectsREST(ctx context.Context, client *gitlab.Client, config Config, group *gitlab.Group) ([]*RepoRecord, error) {
+	var records []*RepoRecord
+	opt := &gitlab.ListGroupProjectsOptions{
+		ListOptions: gitlab.ListOptions{
+			PerPage: 100,
+			Page:    1,
+		},
+		IncludeSubGroups: gitlab.Bool(true), // Include subgroups
+	}
+
+	for {
+		var projects []*gitlab.Project
+		var resp *gitlab.Response
+		err := retryAPI(ctx, config.Retry, "listing group projects", func() (err error) {
+			projects, resp, err = client.Groups.ListGroupProjects(group.ID, opt, gitlab.WithContext(ctx))
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, project := range projects {
+			records = append(records, newGitLabRecord(project, config))
+		}
+
+		if resp.NextPage == 0 {
+			return records, nil
+		}
+		opt.Page = resp.NextPage
+	}
 }
 
 // filterProductionProjects checks each project for .catalog.yml with lifecycle: production
-func filterProductionProjects(client *gitlab.Client, projects []*gitlab.Project) []*gitlab.Project {
-	var productionProjects []*gitlab.Project
+// Projects that are filtered out are marked as skipped.
+func filterProductionProjects(ctx context.Context, client *gitlab.Client, projects []*RepoRecord, retry RetryPolicy) []*RepoRecord {
+	var productionProjects []*RepoRecord
 
-	for i, project := range projects {
-		fmt.Printf("[%d/%d] Checking %s for .catalog.yml...", i+1, len(projects), project.Name)
+	for i, rec := range projects {
+		if ctx.Err() != nil {
+			break
+		}
+		fmt.Printf("[%d/%d] Checking %s for .catalog.yml...", i+1, len(projects), rec.Name)
 		
-		isProduction, err := checkGitLabCatalogFile(client, project.ID)
+		catalog, err := checkGitLabCatalogFile(ctx, client, retry, rec)
 		if err != nil {
 			fmt.Printf(" ❌ Error: %v\n", err)
+			rec.Outcome = outcomeSkipped
+			rec.Reason = "catalog check failed"
+			rec.CatalogError = err.Error()
 			continue
 		}
+		rec.Catalog = catalog
 
-		if isProduction {
+		if isProductionCatalog(catalog) {
 			fmt.Printf(" ✅ Production lifecycle found\n")
-			productionProjects = append(productionProjects, project)
+			productionProjects = append(productionProjects, rec)
 		} else {
 			fmt.Printf(" ⏭️  Not production or no .catalog.yml\n")
+			rec.Outcome = outcomeSkipped
+			rec.Reason = "not production"
 		}
 	}
 
 	return productionProjects
 }
 
-// checkGitLabCatalogFile fetches and parses .catalog.yml to check for lifecycle: production
-func checkGitLabCatalogFile(client *gitlab.Client, projectID int) (bool, error) {
-	// Try to get .catalog.yml file from the repository
-	file, resp, err := client.RepositoryFiles.GetFile(projectID, ".catalog.yml", &gitlab.GetFileOptions{
-		Ref: gitlab.String("main"), // Try main branch first
+// checkGitLabCatalogFile fetches and parses .catalog.yml from the default branch, returning nil if
+// the project has none
+func checkGitLabCatalogFile(ctx context.Context, client *gitlab.Client, retry RetryPolicy, rec *RepoRecord) (*CatalogYAML, error) {
+	// Empty projects have no default branch and no files
+	if rec.DefaultBranch == "" {
+		return nil, nil
+	}
+
+	var file *gitlab.File
+	var resp *gitlab.Response
+	err := retryAPI(ctx, retry, fmt.Sprintf("fetching .catalog.yml of project %d", rec.ID), func() (err error) {
+		file, resp, err = client.RepositoryFiles.GetFile(int(rec.ID), ".catalog.yml", &gitlab.GetFileOptions{
+			Ref: gitlab.String(rec.DefaultBranch),
+		}, gitlab.WithContext(ctx))
+		return err
 	})
 	if err != nil {
-		// If main branch fails, try master branch
 		if resp != nil && resp.StatusCode == 404 {
-			file, resp, err = client.RepositoryFiles.GetFile(projectID, ".catalog.yml", &gitlab.GetFileOptions{
-				Ref: gitlab.String("master"),
-			})
-			if err != nil {
-				if resp != nil && resp.StatusCode == 404 {
-					return false, nil // File not found, not an error
-				}
-				return false, fmt.Errorf("failed to fetch .catalog.yml: %w", err)
-			}
-		} else {
-			return false, fmt.Errorf("failed to fetch .catalog.yml: %w", err)
+			return nil, nil // File not found, not an error
 		}
+		return nil, fmt.Errorf("failed to fetch .catalog.yml: %w", err)
 	}
 
 	if file == nil {
-		return false, nil // File not found
+		return nil, nil // File not found
 	}
 
 	// Decode content (GitLab API returns base64 encoded content)
 	content, err := base64.StdEncoding.DecodeString(file.Content)
 	if err != nil {
-		return false, fmt.Errorf("failed to decode file content: %w", err)
+		return nil, fmt.Errorf("failed to decode file content: %w", err)
 	}
 
 	// Parse YAML
-	var catalog CatalogYAML
-	if err := yaml.Unmarshal(content, &catalog); err != nil {
-		return false, fmt.Errorf("failed to parse YAML: %w", err)
+	return parseCatalog(content)
+}
+
+// newGitLabRecord converts a GitLab project into a platform-neutral record
+func newGitLabRecord(project *gitlab.Project, config Config) *RepoRecord {
+	namespace := ""
+	if project.Namespace != nil {
+		namespace = project.Namespace.FullPath
 	}
 
-	// Check if lifecycle is production
-	return catalog.Component.Lifecycle == "production", nil
+	return &RepoRecord{
+		Platform:      "gitlab",
+		ID:            int64(project.ID),
+		Namespace:     namespace,
+		Name:          project.Name,
+		WebURL:        project.WebURL,
+		HTTPSURL:      getGitLabCloneURL(project, false),
+		SSHURL:        getGitLabCloneURL(project, true),
+		DefaultBranch: project.DefaultBranch,
+		Visibility:    string(project.Visibility),
+		Archived:      project.Archived,
+		LocalPath:     localRepoPath(config, project.Name),
+	}
 }
 
 func getGitLabCloneURL(project *gitlab.Project, useSSH bool) string {
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gitlabGraphQLSchemaQuery checks that the instance's GraphQL API can fetch blobs, which older GitLab versions can't
+const gitlabGraphQLSchemaQuery = `query { __type(name: "Repository") { fields { name } } }`
+
+// gitlabProjectsQuery pages through the projects of a group and its subgroups and fetches the
+// .catalog.yml of the default branch in the same query. Pages are kept at 50 projects to stay
+// below GitLab's query complexity limit.
+const gitlabProjectsQuery = `query($group: ID!, $cursor: String) {
+  group(fullPath: $group) {
+    projects(includeSubgroups: true, first: 50, after: $cursor) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        id
+        name
+        webUrl
+        httpUrlToRepo
+        sshUrlToRepo
+        visibility
+        archived
+        namespace { fullPath }
+        repository {
+          rootRef
+          blobs(paths: [".catalog.yml"]) { nodes { rawTextBlob } }
+        }
+      }
+    }
+  }
+}`
+
+// gitlabProjectNode is a project returned by gitlabProjectsQuery
+type gitlabProjectNode struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	WebURL        string `json:"webUrl"`
+	HTTPURLToRepo string `json:"httpUrlToRepo"`
+	SSHURLToRepo  string `json:"sshUrlToRepo"`
+	Visibility    string `json:"visibility"`
+	Archived      bool   `json:"archived"`
+	Namespace     *struct {
+		FullPath string `json:"fullPath"`
+	} `json:"namespace"`
+	Repository *struct {
+		RootRef *string `json:"rootRef"`
+		Blobs   struct {
+			Nodes []struct {
+				RawTextBlob *string `json:"rawTextBlob"`
+			} `json:"nodes"`
+		} `json:"blobs"`
+	} `json:"repository"`
+}
+
+// gitlabGraphQLEndpoint returns the GraphQL endpoint of a GitLab instance
+func gitlabGraphQLEndpoint(gitlabURL string) string {
+	return strings.TrimRight(gitlabURL, "/") + "/api/graphql"
+}
+
+// gitlabGraphQLHeader returns the authentication header for GitLab GraphQL requests
+func gitlabGraphQLHeader(token string) http.Header {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return header
+}
+
+// checkGitLabGraphQL verifies that the instance supports the GraphQL queries used to list projects
+func checkGitLabGraphQL(ctx context.Context, httpClient *http.Client, config Config) error {
+	var data struct {
+		Type *struct {
+			Fields []struct {
+				Name string `json:"name"`
+			} `json:"fields"`
+		} `json:"__type"`
+	}
+	err := retryAPI(ctx, config.Retry, "checking GraphQL support", func() error {
+		return graphQLRequest(ctx, httpClient, gitlabGraphQLEndpoint(config.GitLabURL), gitlabGraphQLHeader(config.Token),
+			gitlabGraphQLSchemaQuery, nil, &data)
+	})
+	if err != nil {
+		return err
+	}
+	if data.Type != nil {
+		for _, field := range data.Type.Fields {
+			if field.Name == "blobs" {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("GraphQL API of this GitLab version can't fetch repository blobs")
+}
+
+// listGitLabProjectsGraphQL lists the projects of a group and its subgroups together with their catalogs
+func listGitLabProjectsGraphQL(ctx context.Context, httpClient *http.Client, config Config, groupPath string) ([]*RepoRecord, error) {
+	endpoint := gitlabGraphQLEndpoint(config.GitLabURL)
+	header := gitlabGraphQLHeader(config.Token)
+
+	var records []*RepoRecord
+	variables := map[string]interface{}{"group": groupPath, "cursor": nil}
+	for page := 1; ; page++ {
+		var data struct {
+			Group *struct {
+				Projects struct {
+					PageInfo struct {
+						HasNextPage bool   `json:"hasNextPage"`
+						EndCursor   string `json:"endCursor"`
+					} `json:"pageInfo"`
+					Nodes []gitlabProjectNode `json:"nodes"`
+				} `json:"projects"`
+			} `json:"group"`
+		}
+		err := retryAPI(ctx, config.Retry, fmt.Sprintf("listing group projects (page %d)", page), func() error {
+			return graphQLRequest(ctx, httpClient, endpoint, header, gitlabProjectsQuery, variables, &data)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if data.Group == nil {
+			return nil, fmt.Errorf("group '%s' not found", groupPath)
+		}
+
+		for _, node := range data.Group.Projects.Nodes {
+			records = append(records, newGitLabGraphQLRecord(node, config))
+		}
+
+		pageInfo := data.Group.Projects.PageInfo
+		if !pageInfo.HasNextPage {
+			return records, nil
+		}
+		variables["cursor"] = pageInfo.EndCursor
+	}
+}
+
+// newGitLabGraphQLRecord converts a GraphQL project node into a platform-neutral record
+func newGitLabGraphQLRecord(node gitlabProjectNode, config Config) *RepoRecord {
+	// Global IDs look like gid://gitlab/Project/123
+	id, _ := strconv.ParseInt(node.ID[strings.LastIndex(node.ID, "/")+1:], 10, 64)
+
+	rec := &RepoRecord{
+		Platform:   "gitlab",
+		ID:         id,
+		Name:       node.Name,
+		WebURL:     node.WebURL,
+		HTTPSURL:   node.HTTPURLToRepo,
+		SSHURL:     node.SSHURLToRepo,
+		Visibility: node.Visibility,
+		Archived:   node.Archived,
+		LocalPath:  localRepoPath(config, node.Name),
+	}
+	if node.Namespace != nil {
+		rec.Namespace = node.Namespace.FullPath
+	}
+
+	if node.Repository == nil {
+		return rec
+	}
+	if node.Repository.RootRef != nil {
+		rec.DefaultBranch = *node.Repository.RootRef
+	}
+	if blobs := node.Repository.Blobs.Nodes; len(blobs) > 0 && blobs[0].RawTextBlob != nil {
+		catalog, err := parseCatalog([]byte(*blobs[0].RawTextBlob))
+		if err != nil {
+			rec.CatalogError = err.Error()
+		} else {
+			rec.Catalog = catalog
+		}
+	}
+	return rec
+}
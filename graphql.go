@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// API flavors selectable with -api
+const (
+	apiAuto    = "auto"    // GraphQL when available, REST otherwise
+	apiREST    = "rest"    // REST only
+	apiGraphQL = "graphql" // GraphQL only
+)
+
+// GraphQLError is a failed GraphQL request: an HTTP error status, or errors reported in the response body
+type GraphQLError struct {
+	Response *http.Response // Set for HTTP error statuses
+	Messages []string
+}
+
+func (e *GraphQLError) Error() string {
+	if e.Response != nil {
+		message := strings.Join(e.Messages, "; ")
+		if message == "" {
+			return fmt.Sprintf("GraphQL request failed: %s", e.Response.Status)
+		}
+		return fmt.Sprintf("GraphQL request failed: %s: %s", e.Response.Status, message)
+	}
+	return fmt.Sprintf("GraphQL request failed: %s", strings.Join(e.Messages, "; "))
+}
+
+// graphQLRequest posts a GraphQL query and decodes the data of the response into out
+func graphQLRequest(ctx context.Context, httpClient *http.Client, endpoint string, header http.Header, query string, variables map[string]interface{}, out interface{}) error {
+	payload, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		message := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &result) == nil && len(result.Errors) > 0 {
+			message = result.Errors[0].Message
+		}
+		return &GraphQLError{Response: resp, Messages: []string{message}}
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to decode GraphQL response: %w", err)
+	}
+	if len(result.Errors) > 0 {
+		messages := make([]string, 0, len(result.Errors))
+		for _, e := range result.Errors {
+			messages = append(messages, e.Message)
+		}
+		return &GraphQLError{Messages: messages}
+	}
+	return json.Unmarshal(result.Data, out)
+}
+
+// selectProductionRecords picks the production repositories using catalogs that were fetched
+// while listing. Repositories that are filtered out are marked as skipped.
+func selectProductionRecords(records []*RepoRecord) []*RepoRecord {
+	var productionRepos []*RepoRecord
+	for _, rec := range records {
+		switch {
+		case rec.CatalogError != "":
+			fmt.Printf("  %s: ❌ Error: %s\n", rec.Name, rec.CatalogError)
+			rec.Outcome = outcomeSkipped
+			rec.Reason = "catalog check failed"
+		case isProductionCatalog(rec.Catalog):
+			productionRepos = append(productionRepos, rec)
+		default:
+			rec.Outcome = outcomeSkipped
+			rec.Reason = "not production"
+		}
+	}
+	return productionRepos
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Inventory is the machine-readable record of every repository discovered during a run
+type Inventory struct {
+	GeneratedAt  time.Time        `json:"generated_at" yaml:"generated_at"`
+	Repositories []InventoryEntry `json:"repositories" yaml:"repositories"`
+}
+
+// InventoryEntry describes a single repository in the inventory
+type InventoryEntry struct {
+	Platform      string            `json:"platform" yaml:"platform"`
+	ID            int64             `json:"id" yaml:"id"`
+	Namespace     string            `json:"namespace" yaml:"namespace"`
+	Name          string            `json:"name" yaml:"name"`
+	WebURL        string            `json:"web_url" yaml:"web_url"`
+	HTTPSURL      string            `json:"https_url" yaml:"https_url"`
+	SSHURL        string            `json:"ssh_url" yaml:"ssh_url"`
+	DefaultBranch string            `json:"default_branch" yaml:"default_branch"`
+	Visibility    string            `json:"visibility" yaml:"visibility"`
+	Archived      bool              `json:"archived" yaml:"archived"`
+	HasSubmodules bool              `json:"has_submodules" yaml:"has_submodules"`
+	UsesLFS       bool              `json:"uses_lfs" yaml:"uses_lfs"`
+	LocalPath     string            `json:"local_path" yaml:"local_path"`
+	Outcome       string            `json:"outcome" yaml:"outcome"`
+	Reason        string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+	Catalog       *InventoryCatalog `json:"catalog,omitempty" yaml:"catalog,omitempty"`
+	CatalogError  string            `json:"catalog_error,omitempty" yaml:"catalog_error,omitempty"`
+}
+
+// InventoryCatalog holds the catalog fields exported to the inventory
+type InventoryCatalog struct {
+	Type      string   `json:"type" yaml:"type"`
+	Name      string   `json:"name" yaml:"name"`
+	Service   string   `json:"service" yaml:"service"`
+	Team      string   `json:"team" yaml:"team"`
+	Lifecycle string   `json:"lifecycle" yaml:"lifecycle"`
+	Tags      []string `json:"tags" yaml:"tags"`
+}
+
+// inventoryCSVHeader lists the CSV columns in output order
+var inventoryCSVHeader = []string{
+	"platform", "id", "namespace", "name", "web_url", "https_url", "ssh_url",
+	"default_branch", "visibility", "archived", "has_submodules", "uses_lfs", "local_path", "outcome", "reason",
+	"catalog_type", "catalog_name", "catalog_service", "catalog_team", "catalog_lifecycle",
+	"catalog_tags", "catalog_error",
+}
+
+// inventoryFormat returns the inventory format, inferring it from the file extension when unset
+func inventoryFormat(path, format string) (string, error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".csv":
+			format = "csv"
+		case ".yml", ".yaml":
+			format = "yaml"
+		default:
+			format = "json"
+		}
+	}
+
+	format = strings.ToLower(format)
+	switch format {
+	case "json", "csv", "yaml":
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid inventory format '%s'. Must be 'json', 'csv' or 'yaml'", format)
+}
+
+// fillLocalCatalogs reads .catalog.yml from local clones for records whose catalog was not fetched remotely
+func fillLocalCatalogs(records []*RepoRecord) {
+	for _, rec := range records {
+		if rec.Catalog != nil || rec.CatalogError != "" {
+			continue
+		}
+		if rec.Outcome != outcomeCloned && rec.Outcome != outcomeUpdated && rec.Outcome != outcomeExists {
+			continue
+		}
+
+		found, catalog, err := readRepoCatalog(rec.LocalPath)
+		if !found {
+			continue
+		}
+		if err != nil {
+			rec.CatalogError = err.Error()
+			continue
+		}
+		rec.Catalog = catalog
+	}
+}
+
+// newInventoryCatalog returns the catalog fields recorded in the inventory, nil without a catalog
+func newInventoryCatalog(catalog *CatalogYAML) *InventoryCatalog {
+	if catalog == nil {
+		return nil
+	}
+	return &InventoryCatalog{
+		Type:      catalog.Type,
+		Name:      catalog.Component.Name,
+		Service:   catalog.Component.Service,
+		Team:      catalog.Component.Team,
+		Lifecycle: catalog.Component.Lifecycle,
+		Tags:      catalog.Component.Tags,
+	}
+}
+
+// newInventory builds an inventory from the records of a run
+func newInventory(records []*RepoRecord) Inventory {
+	inventory := Inventory{
+		GeneratedAt:  time.Now().UTC(),
+		Repositories: make([]InventoryEntry, 0, len(records)),
+	}
+
+	for _, rec := range records {
+		entry := InventoryEntry{
+			Platform:      rec.Platform,
+			ID:            rec.ID,
+			Namespace:     rec.Namespace,
+			Name:          rec.Name,
+			WebURL:        rec.WebURL,
+			HTTPSURL:      rec.HTTPSURL,
+			SSHURL:        rec.SSHURL,
+			DefaultBranch: rec.DefaultBranch,
+			Visibility:    rec.Visibility,
+			Archived:      rec.Archived,
+			HasSubmodules: rec.HasSubmodules,
+			UsesLFS:       rec.UsesLFS,
+			LocalPath:     rec.LocalPath,
+			Outcome:       rec.Outcome,
+			Reason:        rec.Reason,
+			CatalogError:  rec.CatalogError,
+		}
+		entry.Catalog = newInventoryCatalog(rec.Catalog)
+		inventory.Repositories = append(inventory.Repositories, entry)
+	}
+
+	return inventory
+}
+
+// writeRunInventory writes the inventory of a run if -inventory was given, warning when that fails
+func writeRunInventory(config Config, records []*RepoRecord) {
+	if config.InventoryPath == "" {
+		return
+	}
+	fillLocalCatalogs(records)
+	if err := writeInventory(config.InventoryPath, config.InventoryFormat, records); err != nil {
+		log.Printf("Warning: Failed to write inventory: %v", err)
+		return
+	}
+	fmt.Printf("📄 Inventory of %d repositories written to: %s\n", len(records), config.InventoryPath)
+}
+
+// writeInventory writes the inventory of all discovered repositories in the given format
+func writeInventory(path, format string, records []*RepoRecord) error {
+	inventory := newInventory(records)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create inventory file: %w", err)
+	}
+	defer file.Close()
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(inventory)
+	case "yaml":
+		encoder := yaml.NewEncoder(file)
+		encoder.SetIndent(2)
+		err = encoder.Encode(inventory)
+		if err == nil {
+			err = encoder.Close()
+		}
+	case "csv":
+		err = writeInventoryCSV(file, inventory)
+	default:
+		err = fmt.Errorf("unsupported inventory format: %s", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write inventory: %w", err)
+	}
+
+	return file.Close()
+}
+
+// writeInventoryCSV writes one row per repository with the catalog fields flattened
+func writeInventoryCSV(file *os.File, inventory Inventory) error {
+	writer := csv.NewWriter(file)
+	if err := writer.Write(inventoryCSVHeader); err != nil {
+		return err
+	}
+
+	for _, entry := range inventory.Repositories {
+		catalog := InventoryCatalog{}
+		if entry.Catalog != nil {
+			catalog = *entry.Catalog
+		}
+
+		row := []string{
+			entry.Platform,
+			strconv.FormatInt(entry.ID, 10),
+			entry.Namespace,
+			entry.Name,
+			entry.WebURL,
+			entry.HTTPSURL,
+			entry.SSHURL,
+			entry.DefaultBranch,
+			entry.Visibility,
+			strconv.FormatBool(entry.Archived),
+			strconv.FormatBool(entry.HasSubmodules),
+			strconv.FormatBool(entry.UsesLFS),
+			entry.LocalPath,
+			entry.Outcome,
+			entry.Reason,
+			catalog.Type,
+			catalog.Name,
+			catalog.Service,
+			catalog.Team,
+			catalog.Lifecycle,
+			strings.Join(catalog.Tags, ";"),
+			entry.CatalogError,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// KafkaGraph is the producer → topic → consumer graph aggregated from all catalogs
+type KafkaGraph struct {
+	Services []KafkaService `json:"services"`
+	Topics   []KafkaTopic   `json:"topics"`
+	Edges    []KafkaEdge    `json:"edges"`
+}
+
+// KafkaService is a component that produces or consumes Kafka topics
+type KafkaService struct {
+	Name       string   `json:"name"`
+	Repository string   `json:"repository"`
+	Team       string   `json:"team,omitempty"`
+	Groups     []string `json:"consumer_groups,omitempty"`
+}
+
+// KafkaTopic is a Kafka topic together with the services producing and consuming it
+type KafkaTopic struct {
+	Name      string   `json:"name"`
+	Producers []string `json:"producers"`
+	Consumers []string `json:"consumers"`
+}
+
+// KafkaEdge is a directed edge of the graph: service → topic for producers, topic → service for consumers
+type KafkaEdge struct {
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Kind       string   `json:"kind"`       // produces or consumes
+	Repository string   `json:"repository"` // Repository of the service, which identifies it in the graph
+	Groups     []string `json:"consumer_groups,omitempty"`
+}
+
+// runKafkaGraph implements the kafka-graph report
+func runKafkaGraph(args []string) int {
+	fs := flag.NewFlagSet("kafka-graph", flag.ExitOnError)
+	dir := fs.String("dir", "./repositories", "Directory containing the downloaded repositories")
+	format := fs.String("format", "dot", "Output format: dot, mermaid or json")
+	out := fs.String("out", "", "Write the graph to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: git-repo-downloader report kafka-graph [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Builds a producer → topic → consumer graph from the kafka sections of all .catalog.yml files.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	catalogs, err := loadCatalogs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	graph := buildKafkaGraph(catalogs)
+
+	var render func(io.Writer, KafkaGraph) error
+	switch strings.ToLower(*format) {
+	case "dot":
+		render = renderKafkaDOT
+	case "mermaid":
+		render = renderKafkaMermaid
+	case "json":
+		render = renderKafkaJSON
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid format '%s'. Must be 'dot', 'mermaid' or 'json'\n", *format)
+		return exitError
+	}
+
+	if err := writeReport(*out, func(w io.Writer) error { return render(w, graph) }); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if *out != "" {
+		fmt.Printf("📄 Kafka graph with %d services and %d topics written to: %s\n", len(graph.Services), len(graph.Topics), *out)
+	}
+	return exitOK
+}
+
+// buildKafkaGraph aggregates the kafka sections of all parsed catalogs into a single graph
+func buildKafkaGraph(catalogs []CatalogInfo) KafkaGraph {
+	var graph KafkaGraph
+	topics := make(map[string]*KafkaTopic)
+
+	topic := func(name string) *KafkaTopic {
+		if t, ok := topics[name]; ok {
+			return t
+		}
+		t := &KafkaTopic{Name: name, Producers: []string{}, Consumers: []string{}}
+		topics[name] = t
+		return t
+	}
+
+	for _, info := range catalogs {
+		if info.Catalog == nil {
+			continue
+		}
+
+		// Catalogs with only consumer groups are kept for the shared consumer group check
+		kafka := info.Catalog.Component.Kafka
+		if len(kafka.Producer.Topics) == 0 && len(kafka.Consumer.Topics) == 0 && len(kafka.Consumer.Groups) == 0 {
+			continue
+		}
+
+		service := catalogServiceName(info)
+		graph.Services = append(graph.Services, KafkaService{
+			Name:       service,
+			Repository: info.RepoName,
+			Team:       info.Catalog.Component.Team,
+			Groups:     kafka.Consumer.Groups,
+		})
+
+		for _, name := range uniqueStrings(kafka.Producer.Topics) {
+			t := topic(name)
+			t.Producers = append(t.Producers, service)
+			graph.Edges = append(graph.Edges, KafkaEdge{From: service, To: name, Kind: "produces", Repository: info.RepoName})
+		}
+		for _, name := range uniqueStrings(kafka.Consumer.Topics) {
+			t := topic(name)
+			t.Consumers = append(t.Consumers, service)
+			graph.Edges = append(graph.Edges, KafkaEdge{From: name, To: service, Kind: "consumes", Repository: info.RepoName, Groups: kafka.Consumer.Groups})
+		}
+	}
+
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		graph.Topics = append(graph.Topics, *topics[name])
+	}
+
+	sort.Slice(graph.Services, func(i, j int) bool {
+		a, b := graph.Services[i], graph.Services[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Repository < b.Repository
+	})
+	return graph
+}
+
+// catalogServiceName returns the service name of a catalog, falling back to the component and repository names
+func catalogServiceName(info CatalogInfo) string {
+	if info.Catalog != nil {
+		if info.Catalog.Component.Service != "" {
+			return info.Catalog.Component.Service
+		}
+		if info.Catalog.Component.Name != "" {
+			return info.Catalog.Component.Name
+		}
+	}
+	return info.RepoName
+}
+
+// renderKafkaDOT renders the graph in Graphviz DOT format
+func renderKafkaDOT(w io.Writer, graph KafkaGraph) error {
+	var b strings.Builder
+	b.WriteString("digraph kafka {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [fontname=\"Helvetica\"];\n\n")
+
+	// Services are identified by their repository, several repositories may declare the same service name
+	for _, service := range graph.Services {
+		fmt.Fprintf(&b, "  %q [shape=box, style=rounded, label=%q];\n", "service:"+service.Repository, service.Name)
+	}
+	for _, topic := range graph.Topics {
+		fmt.Fprintf(&b, "  %q [shape=ellipse, style=filled, fillcolor=\"#f0f0f0\", label=%q];\n", "topic:"+topic.Name, topic.Name)
+	}
+	b.WriteString("\n")
+
+	for _, edge := range graph.Edges {
+		if edge.Kind == "produces" {
+			fmt.Fprintf(&b, "  %q -> %q;\n", "service:"+edge.Repository, "topic:"+edge.To)
+			continue
+		}
+		if len(edge.Groups) > 0 {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", "topic:"+edge.From, "service:"+edge.Repository, strings.Join(edge.Groups, ", "))
+		} else {
+			fmt.Fprintf(&b, "  %q -> %q;\n", "topic:"+edge.From, "service:"+edge.Repository)
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderKafkaMermaid renders the graph as a Mermaid flowchart
+func renderKafkaMermaid(w io.Writer, graph KafkaGraph) error {
+	// Mermaid node IDs must be simple identifiers, so services and topics get generated IDs
+	ids := make(map[string]string)
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+
+	for i, service := range graph.Services {
+		id := fmt.Sprintf("s%d", i)
+		ids["service:"+service.Repository] = id
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, mermaidEscape(service.Name))
+	}
+	for i, topic := range graph.Topics {
+		id := fmt.Sprintf("t%d", i)
+		ids["topic:"+topic.Name] = id
+		fmt.Fprintf(&b, "  %s([\"%s\"])\n", id, mermaidEscape(topic.Name))
+	}
+
+	for _, edge := range graph.Edges {
+		if edge.Kind == "produces" {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids["service:"+edge.Repository], ids["topic:"+edge.To])
+			continue
+		}
+		if len(edge.Groups) > 0 {
+			fmt.Fprintf(&b, "  %s -->|\"%s\"| %s\n", ids["topic:"+edge.From], mermaidEscape(strings.Join(edge.Groups, ", ")), ids["service:"+edge.Repository])
+		} else {
+			fmt.Fprintf(&b, "  %s --> %s\n", ids["topic:"+edge.From], ids["service:"+edge.Repository])
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidEscape escapes double quotes, which would terminate a Mermaid label
+func mermaidEscape(label string) string {
+	return strings.ReplaceAll(label, "\"", "#quot;")
+}
+
+// renderKafkaJSON renders the graph as indented JSON
+func renderKafkaJSON(w io.Writer, graph KafkaGraph) error {
+	if graph.Services == nil {
+		graph.Services = []KafkaService{}
+	}
+	if graph.Topics == nil {
+		graph.Topics = []KafkaTopic{}
+	}
+	if graph.Edges == nil {
+		graph.Edges = []KafkaEdge{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(graph)
+}
+
+// uniqueStrings returns the non-empty values in order of first appearance without duplicates
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		unique = append(unique, value)
+	}
+	return unique
+}
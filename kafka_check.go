@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultTopicPattern matches topic names of the form domain.entity.event.vN
+const defaultTopicPattern = `^[a-z0-9-]+\.[a-z0-9-]+\.[a-z0-9-]+\.v[0-9]+$`
+
+// Kafka consistency checks, in report order
+const (
+	checkConsumedNotProduced = "consumed_not_produced"
+	checkProducedNotConsumed = "produced_not_consumed"
+	checkSharedConsumerGroup = "shared_consumer_group"
+	checkInvalidTopicName    = "invalid_topic_name"
+)
+
+// kafkaCheckTitles are the human-readable section titles of each check
+var kafkaCheckTitles = map[string]string{
+	checkConsumedNotProduced: "Topics consumed but never produced",
+	checkProducedNotConsumed: "Topics produced but never consumed",
+	checkSharedConsumerGroup: "Consumer groups shared by multiple services",
+	checkInvalidTopicName:    "Topic names violating the naming pattern",
+}
+
+// KafkaFinding is a single consistency problem found across the catalogs
+type KafkaFinding struct {
+	Check    string   `json:"check"`
+	Subject  string   `json:"subject"` // Topic or consumer group name
+	Services []string `json:"services"`
+	Message  string   `json:"message"`
+}
+
+// runKafkaCheck implements the kafka-check report
+func runKafkaCheck(args []string) int {
+	fs := flag.NewFlagSet("kafka-check", flag.ExitOnError)
+	dir := fs.String("dir", "./repositories", "Directory containing the downloaded repositories")
+	pattern := fs.String("topic-pattern", defaultTopicPattern, "Regular expression topic names must match (empty to disable)")
+	format := fs.String("format", "text", "Output format: text or json")
+	out := fs.String("out", "", "Write the report to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: git-repo-downloader report kafka-check [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Checks the kafka sections of all .catalog.yml files for consistency across the organization.\n")
+		fmt.Fprintf(fs.Output(), "Exits with %d when findings are reported and %d on errors.\n\n", exitFindings, exitError)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var topicPattern *regexp.Regexp
+	if *pattern != "" {
+		var err error
+		topicPattern, err = regexp.Compile(*pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -topic-pattern: %v\n", err)
+			return exitError
+		}
+	}
+
+	var render func(io.Writer, []KafkaFinding) error
+	switch strings.ToLower(*format) {
+	case "text":
+		render = renderKafkaFindingsText
+	case "json":
+		render = renderKafkaFindingsJSON
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid format '%s'. Must be 'text' or 'json'\n", *format)
+		return exitError
+	}
+
+	catalogs, err := loadCatalogs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	findings := checkKafkaConsistency(buildKafkaGraph(catalogs), topicPattern)
+	if err := writeReport(*out, func(w io.Writer) error { return render(w, findings) }); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if len(findings) > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// checkKafkaConsistency runs all consistency checks on the graph; a nil pattern disables the naming check
+func checkKafkaConsistency(graph KafkaGraph, topicPattern *regexp.Regexp) []KafkaFinding {
+	var findings []KafkaFinding
+
+	for _, topic := range graph.Topics {
+		if len(topic.Producers) == 0 {
+			findings = append(findings, KafkaFinding{
+				Check:    checkConsumedNotProduced,
+				Subject:  topic.Name,
+				Services: topic.Consumers,
+				Message:  fmt.Sprintf("consumed by %s but no catalog produces it", strings.Join(topic.Consumers, ", ")),
+			})
+		}
+	}
+
+	for _, topic := range graph.Topics {
+		if len(topic.Consumers) == 0 {
+			findings = append(findings, KafkaFinding{
+				Check:    checkProducedNotConsumed,
+				Subject:  topic.Name,
+				Services: topic.Producers,
+				Message:  fmt.Sprintf("produced by %s but no catalog consumes it", strings.Join(topic.Producers, ", ")),
+			})
+		}
+	}
+
+	groups := make(map[string][]string)
+	for _, service := range graph.Services {
+		for _, group := range uniqueStrings(service.Groups) {
+			groups[group] = append(groups[group], service.Name)
+		}
+	}
+	groupNames := make([]string, 0, len(groups))
+	for group := range groups {
+		groupNames = append(groupNames, group)
+	}
+	sort.Strings(groupNames)
+	for _, group := range groupNames {
+		services := uniqueStrings(groups[group])
+		if len(services) > 1 {
+			findings = append(findings, KafkaFinding{
+				Check:    checkSharedConsumerGroup,
+				Subject:  group,
+				Services: services,
+				Message:  fmt.Sprintf("used by %d services: %s", len(services), strings.Join(services, ", ")),
+			})
+		}
+	}
+
+	if topicPattern != nil {
+		for _, topic := range graph.Topics {
+			if !topicPattern.MatchString(topic.Name) {
+				services := uniqueStrings(append(append([]string{}, topic.Producers...), topic.Consumers...))
+				findings = append(findings, KafkaFinding{
+					Check:    checkInvalidTopicName,
+					Subject:  topic.Name,
+					Services: services,
+					Message:  fmt.Sprintf("does not match %s (used by %s)", topicPattern.String(), strings.Join(services, ", ")),
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+// renderKafkaFindingsText renders the findings grouped by check
+func renderKafkaFindingsText(w io.Writer, findings []KafkaFinding) error {
+	var b strings.Builder
+	b.WriteString("Kafka Topic Consistency Report\n")
+	b.WriteString("==============================\n")
+
+	for _, check := range []string{checkConsumedNotProduced, checkProducedNotConsumed, checkSharedConsumerGroup, checkInvalidTopicName} {
+		var matching []KafkaFinding
+		for _, finding := range findings {
+			if finding.Check == check {
+				matching = append(matching, finding)
+			}
+		}
+
+		if len(matching) == 0 {
+			fmt.Fprintf(&b, "\n✅ %s: none\n", kafkaCheckTitles[check])
+			continue
+		}
+		fmt.Fprintf(&b, "\n❌ %s (%d):\n", kafkaCheckTitles[check], len(matching))
+		for _, finding := range matching {
+			fmt.Fprintf(&b, "   - %s: %s\n", finding.Subject, finding.Message)
+		}
+	}
+
+	fmt.Fprintf(&b, "\nTotal findings: %d\n", len(findings))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderKafkaFindingsJSON renders the findings as indented JSON
+func renderKafkaFindingsJSON(w io.Writer, findings []KafkaFinding) error {
+	if findings == nil {
+		findings = []KafkaFinding{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Findings []KafkaFinding `json:"findings"`
+		Total    int            `json:"total"`
+	}{findings, len(findings)})
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestCheckKafkaConsistency(t *testing.T) {
+	pattern := regexp.MustCompile(defaultTopicPattern)
+
+	tests := []struct {
+		name    string
+		graph   KafkaGraph
+		pattern *regexp.Regexp
+		want    []KafkaFinding
+	}{
+		{
+			name: "consistent",
+			graph: KafkaGraph{
+				Services: []KafkaService{{Name: "billing", Groups: []string{"billing"}}, {Name: "orders"}},
+				Topics:   []KafkaTopic{{Name: "shop.order.created.v1", Producers: []string{"orders"}, Consumers: []string{"billing"}}},
+			},
+			pattern: pattern,
+		},
+		{
+			name: "consumed but not produced",
+			graph: KafkaGraph{
+				Topics: []KafkaTopic{{Name: "shop.order.created.v1", Consumers: []string{"billing", "mailer"}}},
+			},
+			pattern: pattern,
+			want: []KafkaFinding{{
+				Check:    checkConsumedNotProduced,
+				Subject:  "shop.order.created.v1",
+				Services: []string{"billing", "mailer"},
+				Message:  "consumed by billing, mailer but no catalog produces it",
+			}},
+		},
+		{
+			name: "produced but not consumed",
+			graph: KafkaGraph{
+				Topics: []KafkaTopic{{Name: "shop.order.created.v1", Producers: []string{"orders"}}},
+			},
+			pattern: pattern,
+			want: []KafkaFinding{{
+				Check:    checkProducedNotConsumed,
+				Subject:  "shop.order.created.v1",
+				Services: []string{"orders"},
+				Message:  "produced by orders but no catalog consumes it",
+			}},
+		},
+		{
+			name: "shared consumer group",
+			graph: KafkaGraph{
+				Services: []KafkaService{
+					{Name: "billing", Groups: []string{"shared", "billing", "shared"}},
+					{Name: "mailer", Groups: []string{"shared"}},
+				},
+			},
+			pattern: pattern,
+			want: []KafkaFinding{{
+				Check:    checkSharedConsumerGroup,
+				Subject:  "shared",
+				Services: []string{"billing", "mailer"},
+				Message:  "used by 2 services: billing, mailer",
+			}},
+		},
+		{
+			name: "invalid topic name",
+			graph: KafkaGraph{
+				Topics: []KafkaTopic{{Name: "OrderCreated", Producers: []string{"orders"}, Consumers: []string{"billing", "orders"}}},
+			},
+			pattern: pattern,
+			want: []KafkaFinding{{
+				Check:    checkInvalidTopicName,
+				Subject:  "OrderCreated",
+				Services: []string{"orders", "billing"},
+				Message:  "does not match " + defaultTopicPattern + " (used by orders, billing)",
+			}},
+		},
+		{
+			name: "naming check disabled",
+			graph: KafkaGraph{
+				Topics: []KafkaTopic{{Name: "OrderCreated", Producers: []string{"orders"}, Consumers: []string{"billing"}}},
+			},
+		},
+		{
+			name: "findings in report order",
+			graph: KafkaGraph{
+				Topics: []KafkaTopic{
+					{Name: "Orphan", Producers: []string{"orders"}},
+					{Name: "shop.payment.failed.v1", Consumers: []string{"billing"}},
+				},
+			},
+			pattern: pattern,
+			want: []KafkaFinding{
+				{Check: checkConsumedNotProduced, Subject: "shop.payment.failed.v1", Services: []string{"billing"}, Message: "consumed by billing but no catalog produces it"},
+				{Check: checkProducedNotConsumed, Subject: "Orphan", Services: []string{"orders"}, Message: "produced by orders but no catalog consumes it"},
+				{Check: checkInvalidTopicName, Subject: "Orphan", Services: []string{"orders"}, Message: "does not match " + defaultTopicPattern + " (used by orders)"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkKafkaConsistency(tt.graph, tt.pattern)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkKafkaConsistency() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunKafkaCheckExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		catalogs map[string]string
+		args     []string
+		want     int
+	}{
+		{
+			name: "no findings",
+			catalogs: map[string]string{
+				"orders":  "component:\n  name: orders\n  kafka:\n    producer:\n      topics: [shop.order.created.v1]\n",
+				"billing": "component:\n  name: billing\n  kafka:\n    consumer:\n      groups: [billing]\n      topics: [shop.order.created.v1]\n",
+			},
+			want: exitOK,
+		},
+		{
+			name: "findings",
+			catalogs: map[string]string{
+				"billing": "component:\n  name: billing\n  kafka:\n    consumer:\n      topics: [shop.order.created.v1]\n",
+			},
+			want: exitFindings,
+		},
+		{
+			name:     "invalid topic pattern",
+			catalogs: map[string]string{},
+			args:     []string{"-topic-pattern", "("},
+			want:     exitError,
+		},
+		{
+			name:     "invalid format",
+			catalogs: map[string]string{},
+			args:     []string{"-format", "xml"},
+			want:     exitError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for repo, catalog := range tt.catalogs {
+				if err := os.MkdirAll(filepath.Join(dir, repo), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(dir, repo, catalogFileName), []byte(catalog), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			args := append([]string{"-dir", dir, "-out", filepath.Join(t.TempDir(), "report.txt")}, tt.args...)
+			if got := runKafkaCheck(args); got != tt.want {
+				t.Errorf("runKafkaCheck() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// parsedCatalog returns the scan result of a repository with the given catalog
+func parsedCatalog(t *testing.T, repo, content string) CatalogInfo {
+	t.Helper()
+	catalog, err := parseCatalog([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return CatalogInfo{RepoName: repo, HasCatalog: true, Catalog: catalog}
+}
+
+func TestBuildKafkaGraph(t *testing.T) {
+	catalogs := []CatalogInfo{
+		parsedCatalog(t, "orders", "component:\n  service: orders\n  kafka:\n    producer:\n      topics: [shop.order.created.v1, shop.order.created.v1]\n"),
+		parsedCatalog(t, "billing", "component:\n  name: billing\n  team: Payments\n  kafka:\n    consumer:\n      groups: [billing]\n      topics: [shop.order.created.v1]\n"),
+		parsedCatalog(t, "audit", "component:\n  service: audit\n  kafka:\n    consumer:\n      groups: [billing]\n"),
+		parsedCatalog(t, "website", "component:\n  name: website\n"),
+		{RepoName: "broken"},
+	}
+
+	graph := buildKafkaGraph(catalogs)
+
+	var services []string
+	for _, service := range graph.Services {
+		services = append(services, service.Name+"@"+service.Repository)
+	}
+	if got, want := strings.Join(services, ","), "audit@audit,billing@billing,orders@orders"; got != want {
+		t.Errorf("services = %s, want %s", got, want)
+	}
+
+	if len(graph.Topics) != 1 {
+		t.Fatalf("topics = %+v, want shop.order.created.v1 only", graph.Topics)
+	}
+	topic := graph.Topics[0]
+	if strings.Join(topic.Producers, ",") != "orders" || strings.Join(topic.Consumers, ",") != "billing" {
+		t.Errorf("topic = %+v, want produced by orders and consumed by billing", topic)
+	}
+	if len(graph.Edges) != 2 {
+		t.Errorf("edges = %+v, want one per producer and consumer", graph.Edges)
+	}
+
+	// The catalog with only a consumer group takes part in the shared group check
+	findings := checkKafkaConsistency(graph, nil)
+	if len(findings) != 1 || findings[0].Check != checkSharedConsumerGroup || strings.Join(findings[0].Services, ",") != "audit,billing" {
+		t.Errorf("findings = %+v, want group billing shared by audit and billing", findings)
+	}
+}
+
+func TestRenderKafkaGraphSameServiceName(t *testing.T) {
+	// Two repositories declaring the same service must stay two nodes
+	graph := buildKafkaGraph([]CatalogInfo{
+		parsedCatalog(t, "api-v1", "component:\n  service: api\n  kafka:\n    producer:\n      topics: [shop.order.created.v1]\n"),
+		parsedCatalog(t, "api-v2", "component:\n  service: api\n  kafka:\n    consumer:\n      topics: [shop.order.created.v1]\n"),
+	})
+
+	var mermaid strings.Builder
+	if err := renderKafkaMermaid(&mermaid, graph); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{`s0["api"]`, `s1["api"]`, `t0(["shop.order.created.v1"])`, "s0 --> t0", "t0 --> s1"} {
+		if !strings.Contains(mermaid.String(), "  "+line+"\n") {
+			t.Errorf("Mermaid graph is missing %q:\n%s", line, mermaid.String())
+		}
+	}
+
+	var dot strings.Builder
+	if err := renderKafkaDOT(&dot, graph); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{`"service:api-v1" -> "topic:shop.order.created.v1";`, `"topic:shop.order.created.v1" -> "service:api-v2";`} {
+		if !strings.Contains(dot.String(), "  "+line+"\n") {
+			t.Errorf("DOT graph is missing %q:\n%s", line, dot.String())
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runList implements the list command: list and filter repositories like sync, without cloning
+func runList(args []string) int {
+	var config Config
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	registerSourceFlags(fs, &config)
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: git-repo-downloader list [flags]\n\n")
+		fmt.Fprintf(w, "Lists the repositories of a GitHub organization or GitLab group, applying the same filters\n")
+		fmt.Fprintf(w, "as sync (-skip-archived, --prod), without cloning anything.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Exit codes: 0 on success, 2 on invalid flags, 3 when some groups couldn't be listed,")
+		fmt.Fprintln(w, "4 when listing failed, 5 when the run was interrupted.")
+	}
+	fs.Parse(args)
+
+	if err := validateSourceConfig(&config); err != nil {
+		fs.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		return exitError
+	}
+	config.ListOnly = true
+	config.Events = newEventWriter(config.Output)
+
+	if err := setupAPI(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	ctx, cancel := newRunContext(config)
+	defer cancel()
+
+	records, err := discoverRepos(ctx, config)
+	if ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "Error: Failed to list repositories: %s\n", cancelReason(ctx))
+		return exitInterrupted
+	}
+	var groupErr *groupListingError
+	if err != nil && !errors.As(err, &groupErr) {
+		fmt.Fprintf(os.Stderr, "Error: Failed to list repositories: %v\n", err)
+		return exitTotalFailure
+	}
+
+	displayRepoList(records)
+	displayGroupFailures(groupErr)
+	config.Rate.displaySummary()
+	config.Cache.displaySummary()
+	writeRunInventory(config, records)
+	config.Events.summary(config, records, false)
+	if groupErr != nil {
+		return exitPartialFailure
+	}
+	return exitOK
+}
+
+// displayRepoList prints the discovered repositories, selected ones first
+func displayRepoList(records []*RepoRecord) {
+	selected := len(records) - countOutcome(records, outcomeSkipped)
+	fmt.Printf("\n📋 Repositories (%d of %d selected):\n", selected, len(records))
+	for _, rec := range records {
+		if rec.Outcome != outcomeSkipped {
+			fmt.Printf("   ✓ %s [%s] → %s\n", rec.Name, repoListAttributes(rec), rec.WebURL)
+		}
+	}
+	for _, rec := range records {
+		if rec.Outcome == outcomeSkipped {
+			fmt.Printf("   ⏭️  %s [%s] (skipped: %s)\n", rec.Name, repoListAttributes(rec), rec.Reason)
+		}
+	}
+}
+
+// repoListAttributes describes the visibility, default branch and archived state of a repository
+func repoListAttributes(rec *RepoRecord) string {
+	attributes := []string{orDash(rec.Visibility), orDash(rec.DefaultBranch)}
+	if rec.Archived {
+		attributes = append(attributes, "archived")
+	}
+	return strings.Join(attributes, ", ")
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 type Config struct {
@@ -15,178 +18,223 @@ type Config struct {
 	Token        string // Personal access token for authentication
 	TargetDir    string // Target directory for downloaded repositories
 	UseSSH       bool   // Use SSH URLs instead of HTTPS
+	SSHKey       string // Private key for SSH authentication with the go-git backend
+	Backend      string // Clone backend: exec (git binary) or go-git
 	GitLabURL    string // GitLab instance URL (for self-hosted)
+	API          string // API used to list repositories: auto, rest or graphql
 	ProdMode     bool   // Enable production mode to only download repos with lifecycle: production
 	AllGroups    bool   // Download from all groups (GitLab only)
+	SkipArchived bool   // Skip archived repositories
+	DryRun       bool   // List, filter and check catalogs, then print a plan instead of cloning
+	ListOnly     bool   // Only list and filter repositories (list command)
+	Mirror       bool   // Create bare mirror clones (name.git) and update them on later runs
+	Depth        int    // Create shallow clones truncated to this many commits (0 for full history)
+	Unshallow    bool   // Convert existing shallow clones into full clones
+	Filter       string // Partial clone filter: blob:none or tree:0
+	SingleBranch bool   // Only clone the default branch
+
+	Retry        RetryPolicy   // Retries of transient API and clone failures
+	APIBudget    int           // Maximum API requests per run (0 for no limit)
+	Rate         *RateTracker  // Counts API requests and pauses when the rate limit is nearly used up
+	CacheDir     string        // Directory of the API response cache (default: user cache directory)
+	NoCache      bool          // Disable the API response cache
+	Cache        *APICache     // API response cache, nil with -no-cache
+	CloneTimeout time.Duration // Maximum duration of a single clone or update (0 for no limit)
+	TotalTimeout time.Duration // Maximum duration of the whole run (0 for no limit)
+
+	Submodules bool   // Recursively initialize submodules after cloning
+	LFS        string // Git LFS handling: fetch, skip or empty for git's default behavior
+
+	SparsePatterns   string       // Comma-separated sparse-checkout patterns for all repositories
+	SparseConfigPath string       // YAML file with sparse-checkout patterns per catalog type
+	Sparse           SparseConfig // Sparse-checkout patterns loaded from the two options above
+
+	Output     string           // Output mode: text, or json for one JSON event per line
+	Events     *EventWriter     // Writes the -output=json events, nil for text output
+	NoProgress bool             // Print plain progress lines even on a terminal
+	Progress   *ProgressDisplay // Live progress view, nil when progress is printed as plain lines
+
+	FailuresPath string // File the failed repositories are written to (default: in the target directory)
+	RetryFailed  bool   // Only clone the repositories of the failures file again, without listing
+
+	InventoryPath   string // File to write the repository inventory to (optional)
+	InventoryFormat string // Inventory format: json, csv or yaml (inferred from the file extension if empty)
 }
 
-type CatalogInfo struct {
-	RepoName     string
-	RepoPath     string
-	CatalogPath  string
-	HasCatalog   bool
-}
+func main() {
+	// Without a command the flags are those of sync, so existing invocations keep working
+	command, args := "sync", os.Args[1:]
+	if len(args) == 0 {
+		printUsage(os.Stderr)
+		os.Exit(exitError)
+	}
+	if !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
 
-// CatalogYAML represents the structure of .catalog.yml files
-type CatalogYAML struct {
-	Version   string `yaml:"version"`
-	Type      string `yaml:"type"`
-	Component struct {
-		Name        string   `yaml:"name"`
-		Service     string   `yaml:"service"`
-		Team        string   `yaml:"team"`
-		Description string   `yaml:"description"`
-		Tags        []string `yaml:"tags"`
-		Lifecycle   string   `yaml:"lifecycle"`
-		Kafka       struct {
-			Consumer struct {
-				Groups []string `yaml:"groups"`
-				Topics []string `yaml:"topics"`
-			} `yaml:"consumer"`
-			Producer struct {
-				Topics []string `yaml:"topics"`
-			} `yaml:"producer"`
-		} `yaml:"kafka"`
-	} `yaml:"component"`
-}
+	if command == "help" {
+		if len(args) == 0 {
+			printUsage(os.Stdout)
+			os.Exit(exitOK)
+		}
+		// Show the help of a command, e.g. "help sync"
+		command, args = args[0], []string{"-h"}
+	}
 
-func main() {
-	var config Config
+	switch command {
+	case "sync":
+		os.Exit(runSync(args))
+	case "list":
+		os.Exit(runList(args))
+	case "scan":
+		os.Exit(runScan(args))
+	case "report":
+		os.Exit(runReport(args))
+	case "kafka-graph", "kafka-check", "owners":
+		// Reports used to be top-level commands
+		os.Exit(runReport(append([]string{command}, args...)))
+	case "catalog-stubs":
+		os.Exit(runCatalogStubs(args))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown command '%s'\n\n", command)
+		printUsage(os.Stderr)
+		os.Exit(exitError)
+	}
+}
 
-	// Parse command line flags
-	flag.StringVar(&config.Platform, "platform", "", "Platform to use: github or gitlab (required)")
-	flag.StringVar(&config.Organization, "org", "", "Organization (GitHub) or Group (GitLab) name (required)")
-	flag.StringVar(&config.Token, "token", "", "Personal access token for authentication")
-	flag.StringVar(&config.TargetDir, "dir", "./repositories", "Target directory for downloaded repositories")
-	flag.BoolVar(&config.UseSSH, "ssh", false, "Use SSH URLs instead of HTTPS")
-	flag.StringVar(&config.GitLabURL, "gitlab-url", "https://gitlab.com", "GitLab instance URL (for self-hosted)")
-	flag.BoolVar(&config.ProdMode, "prod", false, "Enable production mode to only download repositories with component.lifecycle: production")
-	flag.BoolVar(&config.AllGroups, "all-groups", false, "Download from all groups (GitLab only)")
+// printUsage prints the available commands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Git Repository Downloader")
+	fmt.Fprintln(w, "=========================")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Downloads all repositories from GitHub organizations or GitLab groups.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  git-repo-downloader <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  sync           Clone new repositories and update existing mirrors and shallow clones (default)")
+	fmt.Fprintln(w, "  list           List and filter repositories without cloning")
+	fmt.Fprintln(w, "  scan           Scan the .catalog.yml files of already downloaded repositories (no network)")
+	fmt.Fprintln(w, "  report         Generate reports from downloaded repositories: kafka-graph, kafka-check, owners")
+	fmt.Fprintln(w, "  catalog-stubs  Open PRs/MRs adding .catalog.yml to repositories missing one")
+	fmt.Fprintln(w, "  help           Show this help, or the help of a command: help <command>")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Without a command the flags are those of sync, e.g.:")
+	fmt.Fprintln(w, "  git-repo-downloader -platform=github -org=kubernetes")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit codes: 0 on success, 1 when scan or report find problems, 2 on invalid flags and errors;")
+	fmt.Fprintln(w, "sync also exits with 3 when some repositories failed, 4 when all failed and 5 when interrupted.")
+}
 
-	flag.Parse()
+// registerSourceFlags registers the flags that select, list and filter repositories, shared by sync and list
+func registerSourceFlags(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.Platform, "platform", "", "Platform to use: github or gitlab (required)")
+	fs.StringVar(&config.Organization, "org", "", "Organization (GitHub) or Group (GitLab) name (required)")
+	fs.StringVar(&config.Token, "token", "", "Personal access token for authentication")
+	fs.StringVar(&config.TargetDir, "dir", "./repositories", "Target directory for downloaded repositories")
+	fs.StringVar(&config.API, "api", apiAuto, "API used to list repositories and fetch catalogs: auto (GraphQL when available), rest or graphql")
+	fs.StringVar(&config.GitLabURL, "gitlab-url", "https://gitlab.com", "GitLab instance URL (for self-hosted)")
+	fs.BoolVar(&config.ProdMode, "prod", false, "Enable production mode to only download repositories with component.lifecycle: production")
+	fs.BoolVar(&config.AllGroups, "all-groups", false, "Download from all groups (GitLab only)")
+	fs.BoolVar(&config.SkipArchived, "skip-archived", false, "Skip archived repositories")
+	fs.IntVar(&config.Retry.Retries, "retries", 3, "Retries of transient API and clone failures (0 to disable)")
+	fs.DurationVar(&config.Retry.BaseDelay, "retry-delay", 2*time.Second, "Initial delay between retries, doubled for every further retry")
+	fs.StringVar(&config.CacheDir, "cache-dir", "", "Directory of the API response cache (default: git-repo-downloader in the user cache directory)")
+	fs.BoolVar(&config.NoCache, "no-cache", false, "Disable the API response cache and conditional requests")
+	fs.IntVar(&config.APIBudget, "api-budget", 0, "Maximum API requests per run (default: no limit)")
+	fs.DurationVar(&config.TotalTimeout, "total-timeout", 0, "Maximum duration of the whole run, e.g. 2h (default: no limit)")
+	fs.StringVar(&config.Output, "output", outputText, "Output: text, or json for one JSON event per line on stdout (human-readable output moves to stderr)")
+	fs.StringVar(&config.InventoryPath, "inventory", "", "Write an inventory of all discovered repositories to this file")
+	fs.StringVar(&config.InventoryFormat, "inventory-format", "", "Inventory format: json, csv or yaml (default: inferred from the -inventory file extension)")
+}
 
-	// Show help if no arguments or missing required flags
-	if len(os.Args) == 1 || config.Platform == "" || (config.Organization == "" && !config.AllGroups) {
-		fmt.Println("Git Repository Downloader")
-		fmt.Println("=========================")
-		fmt.Println()
-		fmt.Println("Downloads all repositories from GitHub organizations or GitLab groups.")
-		fmt.Println()
-		fmt.Println("Usage:")
-		flag.PrintDefaults()
-		fmt.Println()
-		fmt.Println("Examples:")
-		fmt.Println("  # Download public repositories from GitHub")
-		fmt.Println("  git-repo-downloader -platform=github -org=kubernetes")
-		fmt.Println()
-		fmt.Println("  # Download all repositories with authentication")
-		fmt.Println("  git-repo-downloader -platform=github -org=mycompany -token=ghp_xxxx")
-		fmt.Println()
-		fmt.Println("  # Download from GitLab group")
-		fmt.Println("  git-repo-downloader -platform=gitlab -org=mygroup -token=glpat_xxxx")
-		fmt.Println()
-		fmt.Println("  # Download to specific directory using SSH")
-		fmt.Println("  git-repo-downloader -platform=github -org=myorg -token=ghp_xxxx -dir=~/repos -ssh")
-		fmt.Println()
-		fmt.Println("  # Download from self-hosted GitLab")
-		fmt.Println("  git-repo-downloader -platform=gitlab -org=mygroup -token=glpat_xxxx -gitlab-url=https://gitlab.company.com")
-		fmt.Println()
-		fmt.Println("  # Download only production repositories (with component.lifecycle: production)")
-		fmt.Println("  git-repo-downloader -platform=github -org=myorg -token=ghp_xxxx --prod")
-		fmt.Println()
-		fmt.Println("  # Download from ALL GitLab groups (auto-discover)")
-		fmt.Println("  git-repo-downloader -platform=gitlab -token=glpat_xxxx -gitlab-url=https://gitlab.company.com --all-groups")
-		fmt.Println()
-		
-		if config.Platform == "" {
-			fmt.Println("Error: -platform flag is required")
-		}
-		if config.Organization == "" && !config.AllGroups {
-			fmt.Println("Error: -org flag is required (or use --all-groups for GitLab)")
-		}
-		if config.AllGroups && config.Platform != "gitlab" {
-			fmt.Println("Error: --all-groups flag only works with -platform=gitlab")
-		}
-		os.Exit(1)
+// validateSourceConfig checks the flags registered by registerSourceFlags and expands ~ in paths
+func validateSourceConfig(config *Config) error {
+	if config.Platform == "" {
+		return fmt.Errorf("-platform flag is required")
+	}
+	if config.Organization == "" && !config.AllGroups {
+		return fmt.Errorf("-org flag is required (or use --all-groups for GitLab)")
 	}
 
 	// Validate platform
 	config.Platform = strings.ToLower(config.Platform)
 	if config.Platform != "github" && config.Platform != "gitlab" {
-		log.Fatalf("Invalid platform '%s'. Must be 'github' or 'gitlab'", config.Platform)
+		return fmt.Errorf("invalid platform '%s'. Must be 'github' or 'gitlab'", config.Platform)
 	}
 
 	// Validate all-groups flag
 	if config.AllGroups && config.Platform != "gitlab" {
-		log.Fatalf("--all-groups flag only works with GitLab platform")
+		return fmt.Errorf("--all-groups flag only works with GitLab platform")
 	}
 
-	// Expand ~ in directory path
-	if strings.HasPrefix(config.TargetDir, "~/") {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			log.Fatalf("Error getting home directory: %v", err)
-		}
-		config.TargetDir = filepath.Join(homeDir, config.TargetDir[2:])
+	switch config.API {
+	case apiAuto, apiREST, apiGraphQL:
+	default:
+		return fmt.Errorf("invalid api '%s'. Must be 'auto', 'rest' or 'graphql'", config.API)
 	}
-
-	// Create target directory if it doesn't exist
-	if err := os.MkdirAll(config.TargetDir, 0755); err != nil {
-		log.Fatalf("Error creating target directory '%s': %v", config.TargetDir, err)
+	if config.API == apiGraphQL && config.Platform == "github" && config.Token == "" {
+		return fmt.Errorf("-api=graphql requires a token, the GitHub GraphQL API doesn't allow anonymous access")
 	}
 
-	// Print configuration
-	fmt.Printf("Git Repository Downloader\n")
-	fmt.Printf("=========================\n")
-	fmt.Printf("Platform: %s\n", config.Platform)
-	if config.AllGroups {
-		fmt.Printf("Mode: Auto-discover all groups\n")
-	} else {
-		fmt.Printf("Organization/Group: %s\n", config.Organization)
+	if err := validateOutput(config.Output); err != nil {
+		return err
 	}
-	fmt.Printf("Target directory: %s\n", config.TargetDir)
-	if config.Token != "" {
-		fmt.Printf("Authentication: Using provided token\n")
-	} else {
-		fmt.Printf("Authentication: No token (public repositories only)\n")
+	if config.APIBudget < 0 {
+		return fmt.Errorf("-api-budget must not be negative")
 	}
-	fmt.Printf("Clone method: %s\n", getCloneMethod(config.UseSSH))
-	if config.Platform == "gitlab" {
-		fmt.Printf("GitLab URL: %s\n", config.GitLabURL)
+	if config.Retry.Retries < 0 || config.Retry.BaseDelay < 0 {
+		return fmt.Errorf("-retries and -retry-delay must not be negative")
 	}
-	if config.ProdMode {
-		fmt.Printf("Production mode: Enabled (only downloading repos with lifecycle: production)\n")
+	if config.TotalTimeout < 0 {
+		return fmt.Errorf("-total-timeout must not be negative")
 	}
-	fmt.Println()
 
-	// Download repositories based on platform
-	var err error
-	switch config.Platform {
-	case "github":
-		err = downloadGitHubRepos(config)
-	case "gitlab":
-		err = downloadGitLabRepos(config)
-	default:
-		log.Fatalf("Unsupported platform: %s", config.Platform)
+	// Validate inventory format
+	if config.InventoryPath != "" {
+		format, err := inventoryFormat(config.InventoryPath, config.InventoryFormat)
+		if err != nil {
+			return err
+		}
+		config.InventoryFormat = format
 	}
 
+	// Expand ~ in directory path
+	targetDir, err := expandHomeDir(config.TargetDir)
 	if err != nil {
-		log.Fatalf("Failed to download repositories: %v", err)
+		return fmt.Errorf("error getting home directory: %w", err)
 	}
+	config.TargetDir = targetDir
+	return nil
+}
 
-	fmt.Printf("\n✅ Repository download completed successfully!\n")
-	fmt.Printf("All repositories have been downloaded to: %s\n", config.TargetDir)
+// setupAPI creates the rate limit tracker and the API response cache of a run
+func setupAPI(config *Config) error {
+	config.Rate = newRateTracker(config.APIBudget)
+	if config.NoCache {
+		return nil
+	}
+	cacheDir, err := expandHomeDir(config.CacheDir)
+	if err != nil {
+		return fmt.Errorf("error getting home directory: %w", err)
+	}
+	if config.Cache, err = newAPICache(cacheDir); err != nil {
+		log.Printf("Warning: API response cache disabled: %v", err)
+	}
+	return nil
+}
 
-	// If production mode is enabled, show final scan results
-	if config.ProdMode {
-		fmt.Printf("\n🔍 Final scan of downloaded repositories...\n")
-		catalogInfo, err := scanForCatalogFiles(config.TargetDir)
-		if err != nil {
-			log.Printf("Warning: Failed to scan for catalog files: %v", err)
-		} else {
-			displayCatalogResults(catalogInfo)
-		}
+// discoverRepos lists the repositories of the configured platform, and clones them unless
+// config.ListOnly or config.DryRun is set
+func discoverRepos(ctx context.Context, config Config) ([]*RepoRecord, error) {
+	switch config.Platform {
+	case "github":
+		return downloadGitHubRepos(ctx, config)
+	case "gitlab":
+		return downloadGitLabRepos(ctx, config)
 	}
+	return nil, fmt.Errorf("unsupported platform: %s", config.Platform)
 }
 
 func getCloneMethod(useSSH bool) string {
@@ -196,85 +244,14 @@ func getCloneMethod(useSSH bool) string {
 	return "HTTPS"
 }
 
-// scanForCatalogFiles scans all repositories in the target directory for .catalog.yml files
-func scanForCatalogFiles(targetDir string) ([]CatalogInfo, error) {
-	var catalogInfo []CatalogInfo
-
-	// Read all entries in the target directory
-	entries, err := os.ReadDir(targetDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read target directory: %w", err)
+// expandHomeDir expands a leading ~/ in a path to the user's home directory
+func expandHomeDir(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
 	}
-
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
-		}
-
-		repoName := entry.Name()
-		repoPath := filepath.Join(targetDir, repoName)
-		catalogPath := filepath.Join(repoPath, ".catalog.yml")
-
-		// Check if .catalog.yml exists
-		info := CatalogInfo{
-			RepoName:    repoName,
-			RepoPath:    repoPath,
-			CatalogPath: catalogPath,
-			HasCatalog:  false,
-		}
-
-		if _, err := os.Stat(catalogPath); err == nil {
-			info.HasCatalog = true
-		}
-
-		catalogInfo = append(catalogInfo, info)
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
 	}
-
-	return catalogInfo, nil
+	return filepath.Join(homeDir, path[2:]), nil
 }
-
-// displayCatalogResults displays the results of the catalog file scan
-func displayCatalogResults(catalogInfo []CatalogInfo) {
-	fmt.Printf("\nCatalog File Scan Results\n")
-	fmt.Printf("=========================\n")
-
-	reposWithCatalog := 0
-	reposWithoutCatalog := 0
-
-	fmt.Printf("Repository Analysis:\n")
-	fmt.Printf("--------------------\n")
-
-	for _, info := range catalogInfo {
-		if info.HasCatalog {
-			fmt.Printf("✅ %s - .catalog.yml found\n", info.RepoName)
-			reposWithCatalog++
-		} else {
-			fmt.Printf("❌ %s - .catalog.yml missing\n", info.RepoName)
-			reposWithoutCatalog++
-		}
-	}
-
-	fmt.Printf("\nSummary:\n")
-	fmt.Printf("--------\n")
-	fmt.Printf("Total repositories scanned: %d\n", len(catalogInfo))
-	fmt.Printf("Repositories with .catalog.yml: %d\n", reposWithCatalog)
-	fmt.Printf("Repositories missing .catalog.yml: %d\n", reposWithoutCatalog)
-
-	if reposWithoutCatalog > 0 {
-		fmt.Printf("\n⚠️  Repositories missing .catalog.yml files:\n")
-		for _, info := range catalogInfo {
-			if !info.HasCatalog {
-				fmt.Printf("   - %s\n", info.RepoName)
-			}
-		}
-	}
-
-	if reposWithCatalog > 0 {
-		fmt.Printf("\n📋 Repositories with .catalog.yml files:\n")
-		for _, info := range catalogInfo {
-			if info.HasCatalog {
-				fmt.Printf("   - %s (%s)\n", info.RepoName, info.CatalogPath)
-			}
-		}
-	}
-} 
\ No newline at end of file
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OwnershipReport groups repositories by the team declared in their catalogs
+type OwnershipReport struct {
+	GeneratedAt  time.Time            `json:"generated_at"`
+	Repositories int                  `json:"repositories"`
+	Teams        []TeamOwnership      `json:"teams"`
+	MissingTeam  []MissingTeam        `json:"missing_team"`
+	Mismatches   []CodeownersMismatch `json:"codeowners_mismatches"`
+}
+
+// TeamOwnership lists the services owned by a single team
+type TeamOwnership struct {
+	Team       string         `json:"team"`
+	Services   []OwnedService `json:"services"`
+	Lifecycles map[string]int `json:"lifecycles"`
+}
+
+// OwnedService is a repository owned by a team
+type OwnedService struct {
+	Repository string `json:"repository"`
+	Service    string `json:"service"`
+	Lifecycle  string `json:"lifecycle"`
+	Type       string `json:"type"`
+}
+
+// MissingTeam is a repository without a team in its catalog
+type MissingTeam struct {
+	Repository string `json:"repository"`
+	Reason     string `json:"reason"`
+}
+
+// CodeownersMismatch is a repository whose catalog team is not among its CODEOWNERS
+type CodeownersMismatch struct {
+	Repository     string   `json:"repository"`
+	Team           string   `json:"team"`
+	CodeownersPath string   `json:"codeowners_path"`
+	Owners         []string `json:"owners"`
+}
+
+// runOwners implements the owners report
+func runOwners(args []string) int {
+	fs := flag.NewFlagSet("owners", flag.ExitOnError)
+	dir := fs.String("dir", "./repositories", "Directory containing the downloaded repositories")
+	format := fs.String("format", "markdown", "Output format: markdown or json")
+	out := fs.String("out", "", "Write the report to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: git-repo-downloader report owners [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Groups repositories by component.team and checks catalog teams against CODEOWNERS.\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	var render func(io.Writer, OwnershipReport) error
+	switch strings.ToLower(*format) {
+	case "markdown", "md":
+		render = renderOwnershipMarkdown
+	case "json":
+		render = renderOwnershipJSON
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid format '%s'. Must be 'markdown' or 'json'\n", *format)
+		return exitError
+	}
+
+	catalogs, err := loadCatalogs(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	report := buildOwnershipReport(catalogs)
+	if err := writeReport(*out, func(w io.Writer) error { return render(w, report) }); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if *out != "" {
+		fmt.Printf("📄 Ownership report for %d teams written to: %s\n", len(report.Teams), *out)
+	}
+	return exitOK
+}
+
+// buildOwnershipReport groups the scanned catalogs by team and compares them with CODEOWNERS
+func buildOwnershipReport(catalogs []CatalogInfo) OwnershipReport {
+	report := OwnershipReport{
+		GeneratedAt:  time.Now().UTC(),
+		Repositories: len(catalogs),
+		Teams:        []TeamOwnership{},
+		MissingTeam:  []MissingTeam{},
+		Mismatches:   []CodeownersMismatch{},
+	}
+	teams := make(map[string]*TeamOwnership)
+
+	for _, info := range catalogs {
+		switch {
+		case !info.HasCatalog:
+			report.MissingTeam = append(report.MissingTeam, MissingTeam{Repository: info.RepoName, Reason: ".catalog.yml missing"})
+			continue
+		case info.ParseError != nil:
+			report.MissingTeam = append(report.MissingTeam, MissingTeam{Repository: info.RepoName, Reason: ".catalog.yml invalid"})
+			continue
+		case strings.TrimSpace(info.Catalog.Component.Team) == "":
+			report.MissingTeam = append(report.MissingTeam, MissingTeam{Repository: info.RepoName, Reason: "component.team not set"})
+			continue
+		}
+
+		component := info.Catalog.Component
+		team, ok := teams[component.Team]
+		if !ok {
+			team = &TeamOwnership{Team: component.Team, Lifecycles: make(map[string]int)}
+			teams[component.Team] = team
+		}
+		team.Services = append(team.Services, OwnedService{
+			Repository: info.RepoName,
+			Service:    catalogServiceName(info),
+			Lifecycle:  component.Lifecycle,
+			Type:       info.Catalog.Type,
+		})
+		lifecycle := component.Lifecycle
+		if lifecycle == "" {
+			lifecycle = "unspecified"
+		}
+		team.Lifecycles[lifecycle]++
+
+		codeowners, err := readCodeowners(info.RepoPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Failed to read CODEOWNERS of %s: %v\n", info.RepoName, err)
+			continue
+		}
+		if codeowners != nil && !teamMatchesOwners(component.Team, codeowners.Owners()) {
+			report.Mismatches = append(report.Mismatches, CodeownersMismatch{
+				Repository:     info.RepoName,
+				Team:           component.Team,
+				CodeownersPath: codeowners.Path,
+				Owners:         codeowners.Owners(),
+			})
+		}
+	}
+
+	for _, team := range teams {
+		sort.Slice(team.Services, func(i, j int) bool { return team.Services[i].Repository < team.Services[j].Repository })
+		report.Teams = append(report.Teams, *team)
+	}
+	sort.Slice(report.Teams, func(i, j int) bool { return report.Teams[i].Team < report.Teams[j].Team })
+
+	return report
+}
+
+// renderOwnershipMarkdown renders the report as Markdown ready to paste into a review document
+func renderOwnershipMarkdown(w io.Writer, report OwnershipReport) error {
+	var b strings.Builder
+	b.WriteString("# Team Ownership Report\n\n")
+	fmt.Fprintf(&b, "_Generated %s_\n\n", report.GeneratedAt.Format("2006-01-02 15:04 MST"))
+
+	b.WriteString("## Summary\n\n")
+	b.WriteString("| Repositories | Teams | Missing team | CODEOWNERS mismatches |\n")
+	b.WriteString("|---|---|---|---|\n")
+	fmt.Fprintf(&b, "| %d | %d | %d | %d |\n\n", report.Repositories, len(report.Teams), len(report.MissingTeam), len(report.Mismatches))
+
+	b.WriteString("## Teams\n")
+	for _, team := range report.Teams {
+		fmt.Fprintf(&b, "\n### %s (%d services)\n\n", markdownEscape(team.Team), len(team.Services))
+
+		lifecycles := make([]string, 0, len(team.Lifecycles))
+		for lifecycle := range team.Lifecycles {
+			lifecycles = append(lifecycles, lifecycle)
+		}
+		sort.Strings(lifecycles)
+		for i, lifecycle := range lifecycles {
+			lifecycles[i] = fmt.Sprintf("%s: %d", lifecycle, team.Lifecycles[lifecycle])
+		}
+		fmt.Fprintf(&b, "Lifecycles: %s\n\n", strings.Join(lifecycles, ", "))
+
+		b.WriteString("| Repository | Service | Lifecycle | Type |\n")
+		b.WriteString("|---|---|---|---|\n")
+		for _, service := range team.Services {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", markdownEscape(service.Repository), markdownEscape(service.Service),
+				markdownEscape(orDash(service.Lifecycle)), markdownEscape(orDash(service.Type)))
+		}
+	}
+
+	b.WriteString("\n## Repositories Missing a Team\n\n")
+	if len(report.MissingTeam) == 0 {
+		b.WriteString("None.\n")
+	} else {
+		b.WriteString("| Repository | Reason |\n")
+		b.WriteString("|---|---|\n")
+		for _, missing := range report.MissingTeam {
+			fmt.Fprintf(&b, "| %s | %s |\n", markdownEscape(missing.Repository), missing.Reason)
+		}
+	}
+
+	b.WriteString("\n## CODEOWNERS Mismatches\n\n")
+	if len(report.Mismatches) == 0 {
+		b.WriteString("None.\n")
+	} else {
+		b.WriteString("| Repository | Catalog team | CODEOWNERS owners |\n")
+		b.WriteString("|---|---|---|\n")
+		for _, mismatch := range report.Mismatches {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownEscape(mismatch.Repository), markdownEscape(mismatch.Team),
+				markdownEscape(strings.Join(mismatch.Owners, ", ")))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscape escapes pipes so values don't break Markdown tables
+func markdownEscape(value string) string {
+	return strings.ReplaceAll(value, "|", "\\|")
+}
+
+// renderOwnershipJSON renders the report as indented JSON
+func renderOwnershipJSON(w io.Writer, report OwnershipReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
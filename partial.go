@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// partialSuffix marks the temporary directories that clones are made in before being renamed into place
+const partialSuffix = ".partial"
+
+// brokenSuffix marks clones without a valid HEAD while a fresh clone is swapped in for them
+const brokenSuffix = ".broken"
+
+// partialRepoPath returns the temporary sibling directory a repository is cloned into, e.g. repos/.api.partial
+func partialRepoPath(repoPath string) string {
+	return filepath.Join(filepath.Dir(repoPath), "."+filepath.Base(repoPath)+partialSuffix)
+}
+
+// brokenRepoPath returns where a clone without a valid HEAD is moved while it is replaced, e.g. repos/.api.broken
+func brokenRepoPath(repoPath string) string {
+	return filepath.Join(filepath.Dir(repoPath), "."+filepath.Base(repoPath)+brokenSuffix)
+}
+
+// repairPartialClones removes the temporary directories that interrupted clones left in the target
+// directory, so those repositories are cloned again. Nothing else is touched. It returns the names
+// of the removed directories.
+func repairPartialClones(targetDir string) ([]string, error) {
+	entries, err := os.ReadDir(targetDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target directory: %w", err)
+	}
+
+	var removed []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || !strings.HasPrefix(name, ".") {
+			continue
+		}
+		if !strings.HasSuffix(name, partialSuffix) && !strings.HasSuffix(name, brokenSuffix) {
+			continue
+		}
+		path := filepath.Join(targetDir, name)
+		if err := os.RemoveAll(path); err != nil {
+			return removed, fmt.Errorf("failed to remove partial clone %s: %w", path, err)
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
+
+// hasBrokenHead reports whether repoPath is a git repository whose HEAD doesn't resolve to a commit,
+// as left by clones killed before they were made atomically. Empty repositories and unborn branches
+// have no commit yet and are not broken; directories that aren't repositories are never reported.
+func hasBrokenHead(repoPath string) bool {
+	if !isGitRepository(repoPath) {
+		return false
+	}
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return true
+	}
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	_, err = repo.CommitObject(head.Hash())
+	return err != nil
+}
+
+// replaceClone swaps the fresh clone at partialPath in for the clone without a valid HEAD at
+// repoPath. The old clone is moved aside first and only removed once the fresh clone is in place.
+func replaceClone(partialPath, repoPath string) error {
+	brokenPath := brokenRepoPath(repoPath)
+	if err := os.RemoveAll(brokenPath); err != nil {
+		return err
+	}
+	if err := os.Rename(repoPath, brokenPath); err != nil {
+		return err
+	}
+	if err := os.Rename(partialPath, repoPath); err != nil {
+		if restoreErr := os.Rename(brokenPath, repoPath); restoreErr != nil {
+			log.Printf("Warning: failed to restore %s from %s: %v", repoPath, brokenPath, restoreErr)
+		}
+		return err
+	}
+	if err := os.RemoveAll(brokenPath); err != nil {
+		// The next run removes it on startup
+		log.Printf("Warning: failed to remove replaced clone %s: %v", brokenPath, err)
+	}
+	return nil
+}
+
+// displayBrokenClones lists the clones whose HEAD didn't resolve to a commit and whether they
+// were replaced by a fresh clone
+func displayBrokenClones(records []*RepoRecord) {
+	var broken []*RepoRecord
+	for _, rec := range records {
+		if rec.BrokenHead {
+			broken = append(broken, rec)
+		}
+	}
+	if len(broken) == 0 {
+		return
+	}
+
+	fmt.Printf("\n🩹 Clones whose HEAD didn't resolve to a commit (%d):\n", len(broken))
+	for _, rec := range broken {
+		switch rec.Outcome {
+		case outcomeCloned:
+			fmt.Printf("   - %s: replaced by a fresh clone\n", rec.Name)
+		case outcomePlannedClone:
+			fmt.Printf("   - %s: would be replaced by a fresh clone\n", rec.Name)
+		default:
+			fmt.Printf("   - %s: kept, the fresh clone didn't complete\n", rec.Name)
+		}
+	}
+}
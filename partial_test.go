@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// breakHead points the checked-out branch of a clone at a commit that doesn't exist
+func breakHead(t *testing.T, repoPath string) {
+	t.Helper()
+	ref := filepath.Join(repoPath, ".git", "refs", "heads", "main")
+	runTestGit(t, repoPath, "pack-refs", "--all", "--prune")
+	if err := os.WriteFile(ref, []byte(strings.Repeat("1", 40)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepairPartialClones(t *testing.T) {
+	targetDir := t.TempDir()
+	for _, name := range []string{".api.partial", ".web.git.partial", ".billing.broken", "notes.partial", "api", ".config"} {
+		if err := os.MkdirAll(filepath.Join(targetDir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(targetDir, ".file.partial"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := repairPartialClones(targetDir)
+	if err != nil {
+		t.Fatalf("repairPartialClones() error = %v", err)
+	}
+	sort.Strings(removed)
+	if got, want := strings.Join(removed, ","), ".api.partial,.billing.broken,.web.git.partial"; got != want {
+		t.Errorf("repairPartialClones() removed %s, want %s", got, want)
+	}
+	for _, name := range []string{"notes.partial", "api", ".config", ".file.partial"} {
+		if _, err := os.Stat(filepath.Join(targetDir, name)); err != nil {
+			t.Errorf("%s was removed: %v", name, err)
+		}
+	}
+}
+
+func TestHasBrokenHead(t *testing.T) {
+	setTestGitIdentity(t)
+	remote := newTestRemote(t, map[string]string{"README.md": "# api\n"})
+
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, path string)
+		want  bool
+	}{
+		{
+			name:  "clone",
+			setup: func(t *testing.T, path string) { runTestGit(t, "", "clone", "--quiet", remote, path) },
+		},
+		{
+			name:  "mirror clone",
+			setup: func(t *testing.T, path string) { runTestGit(t, "", "clone", "--quiet", "--mirror", remote, path) },
+		},
+		{
+			name:  "empty repository",
+			setup: func(t *testing.T, path string) { runTestGit(t, "", "init", "--quiet", path) },
+		},
+		{
+			name: "unborn branch",
+			setup: func(t *testing.T, path string) {
+				runTestGit(t, "", "clone", "--quiet", remote, path)
+				runTestGit(t, path, "checkout", "--quiet", "--orphan", "rewrite")
+			},
+		},
+		{
+			name:  "not a repository",
+			setup: func(t *testing.T, path string) { os.MkdirAll(path, 0755) },
+		},
+		{
+			name: "HEAD points at a missing commit",
+			setup: func(t *testing.T, path string) {
+				runTestGit(t, "", "clone", "--quiet", remote, path)
+				breakHead(t, path)
+			},
+			want: true,
+		},
+		{
+			name: "HEAD file missing",
+			setup: func(t *testing.T, path string) {
+				runTestGit(t, "", "clone", "--quiet", remote, path)
+				os.Remove(filepath.Join(path, ".git", "HEAD"))
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "api")
+			tt.setup(t, path)
+			if got := hasBrokenHead(path); got != tt.want {
+				t.Errorf("hasBrokenHead() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCloneRepositoryReplacesBrokenClone(t *testing.T) {
+	setTestGitIdentity(t)
+	remote := newTestRemote(t, map[string]string{"README.md": "# api\n"})
+
+	tests := []struct {
+		name        string
+		cloneURL    string
+		wantOutcome string
+	}{
+		{name: "fresh clone succeeds", cloneURL: "file://" + filepath.ToSlash(remote), wantOutcome: outcomeCloned},
+		{name: "fresh clone fails", cloneURL: "file://" + filepath.ToSlash(remote) + "-missing", wantOutcome: outcomeFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			repoPath := filepath.Join(targetDir, "api")
+			runTestGit(t, "", "clone", "--quiet", remote, repoPath)
+			breakHead(t, repoPath)
+
+			rec := &RepoRecord{Name: "api", HTTPSURL: tt.cloneURL}
+			outcome, err := cloneRepository(context.Background(), Config{TargetDir: targetDir}, rec)
+			if outcome != tt.wantOutcome {
+				t.Fatalf("cloneRepository() = %s, %v, want %s", outcome, err, tt.wantOutcome)
+			}
+			if !rec.BrokenHead {
+				t.Error("BrokenHead isn't set")
+			}
+			if wantBroken := tt.wantOutcome == outcomeFailed; hasBrokenHead(repoPath) != wantBroken {
+				t.Errorf("hasBrokenHead() after cloning = %v, want %v", !wantBroken, wantBroken)
+			}
+			for _, path := range []string{partialRepoPath(repoPath), brokenRepoPath(repoPath)} {
+				if _, err := os.Stat(path); err == nil {
+					t.Errorf("%s is left behind", path)
+				}
+			}
+		})
+	}
+}
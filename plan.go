@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// skipArchivedRecords marks archived repositories as skipped and returns the remaining ones
+func skipArchivedRecords(records []*RepoRecord) []*RepoRecord {
+	var active []*RepoRecord
+	for _, rec := range records {
+		if rec.Archived {
+			rec.Outcome = outcomeSkipped
+			rec.Reason = "archived"
+			continue
+		}
+		active = append(active, rec)
+	}
+	return active
+}
+
+// planRecord decides what cloneRepository would do with a repository without touching the
+// target directory, and stores it as the outcome of the record
+func planRecord(config Config, rec *RepoRecord) {
+	repoPath := localRepoPath(config, rec.Name)
+	if _, err := os.Stat(repoPath); err != nil {
+		rec.Outcome = outcomePlannedClone
+		rec.Reason = "not present locally"
+		return
+	}
+
+	switch {
+	case hasBrokenHead(repoPath):
+		rec.Outcome = outcomePlannedClone
+		rec.Reason = "HEAD doesn't resolve to a commit, cloned again"
+		rec.BrokenHead = true
+	case config.Mirror:
+		rec.Outcome = outcomePlannedUpdate
+		rec.Reason = "mirror"
+	case isShallowRepository(repoPath) && config.Depth > 0:
+		rec.Outcome = outcomePlannedUpdate
+		rec.Reason = fmt.Sprintf("shallow clone, depth %d", config.Depth)
+	case isShallowRepository(repoPath) && config.Unshallow:
+		rec.Outcome = outcomePlannedUpdate
+		rec.Reason = "shallow clone, converted into a full clone"
+	case isShallowRepository(repoPath):
+		rec.Outcome = outcomePlannedUpdate
+		rec.Reason = "shallow clone"
+	default:
+		rec.Outcome = outcomeExists
+		rec.Reason = "exists"
+	}
+}
+
+// displayPlan prints what a run without -dry-run would do with every discovered repository
+func displayPlan(records []*RepoRecord) {
+	clones := countOutcome(records, outcomePlannedClone)
+	updates := countOutcome(records, outcomePlannedUpdate)
+
+	fmt.Printf("\n📝 Plan (dry run, nothing was cloned or updated)\n")
+	fmt.Printf("================================================\n")
+
+	fmt.Printf("\n📥 To clone (%d):\n", clones)
+	for _, rec := range records {
+		if rec.Outcome == outcomePlannedClone {
+			fmt.Printf("   - %s → %s (%s)\n", rec.Name, rec.LocalPath, rec.Reason)
+		}
+	}
+
+	fmt.Printf("\n🔄 To update (%d):\n", updates)
+	for _, rec := range records {
+		if rec.Outcome == outcomePlannedUpdate {
+			fmt.Printf("   - %s → %s (%s)\n", rec.Name, rec.LocalPath, rec.Reason)
+		}
+	}
+
+	fmt.Printf("\n⏭️  Skipped (%d):\n", len(records)-clones-updates)
+	for _, rec := range records {
+		if rec.Outcome == outcomePlannedClone || rec.Outcome == outcomePlannedUpdate {
+			continue
+		}
+		reason := rec.Reason
+		if reason == "" {
+			reason = "not processed"
+		}
+		fmt.Printf("   - %s → %s (%s)\n", rec.Name, rec.LocalPath, reason)
+	}
+
+	fmt.Printf("\nRun without -dry-run to apply this plan.\n")
+}
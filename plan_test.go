@@ -0,0 +1,33 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanRecordShallowClone(t *testing.T) {
+	setTestGitIdentity(t)
+	remote := newTestRemote(t, map[string]string{"README.md": "# api\n"})
+	targetDir := t.TempDir()
+	runTestGit(t, "", "clone", "--quiet", "--depth=1", "file://"+filepath.ToSlash(remote), filepath.Join(targetDir, "api"))
+
+	tests := []struct {
+		name       string
+		config     Config
+		wantReason string
+	}{
+		{name: "plain sync keeps the depth", config: Config{TargetDir: targetDir}, wantReason: "shallow clone"},
+		{name: "depth", config: Config{TargetDir: targetDir, Depth: 5}, wantReason: "shallow clone, depth 5"},
+		{name: "unshallow", config: Config{TargetDir: targetDir, Unshallow: true}, wantReason: "shallow clone, converted into a full clone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &RepoRecord{Name: "api"}
+			planRecord(tt.config, rec)
+			if rec.Outcome != outcomePlannedUpdate || rec.Reason != tt.wantReason {
+				t.Errorf("planRecord() = %s (%s), want %s (%s)", rec.Outcome, rec.Reason, outcomePlannedUpdate, tt.wantReason)
+			}
+		})
+	}
+}
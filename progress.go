@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// progressRedraw is how often the live progress view is redrawn to update the elapsed time and ETA
+const progressRedraw = 250 * time.Millisecond
+
+// progressBarWidth is the number of characters of the overall progress bar
+const progressBarWidth = 20
+
+var (
+	// gitProgressPattern matches git's transfer progress, e.g. "Receiving objects:  45% (450/1000), 1.20 MiB | 2.40 MiB/s"
+	gitProgressPattern = regexp.MustCompile(`^(?:remote: )?([A-Za-z][A-Za-z ]*):\s+(\d+)% \((\d+/\d+)\)`)
+	// gitThroughputPattern matches the throughput at the end of git's progress lines, e.g. "| 2.40 MiB/s"
+	gitThroughputPattern = regexp.MustCompile(`\|\s*([0-9.]+ \S+/s)`)
+)
+
+// ProgressDisplay is the live view shown while cloning on a terminal: overall progress with elapsed
+// time and ETA above one line per active clone with git's own transfer progress and throughput.
+// Everything printed while it is shown is written above it. A nil ProgressDisplay shows nothing,
+// progress is then printed as plain lines.
+type ProgressDisplay struct {
+	term *os.File // The terminal the view is drawn on
+
+	mu      sync.Mutex
+	total   int
+	done    int
+	failed  int
+	started time.Time
+	active  []*cloneProgress
+	drawn   int // Number of lines of the view currently on the screen
+
+	pipe   *os.File      // Write end of the pipe os.Stdout is redirected to while the view is shown
+	closed chan struct{} // Closed when everything written to the pipe was printed
+	quit   chan struct{} // Stops the redraw ticker
+}
+
+// cloneProgress is the git progress of one active clone
+type cloneProgress struct {
+	display    *ProgressDisplay
+	name       string
+	started    time.Time
+	phase      string // e.g. "Receiving objects"
+	percent    string
+	count      string // e.g. "450/1000"
+	throughput string // e.g. "2.40 MiB/s"
+	partial    []byte // Output after the last line break
+}
+
+// newProgressDisplay returns the live progress view, or nil when it is disabled, the output is
+// JSON or stdout isn't a terminal
+func newProgressDisplay(config Config) *ProgressDisplay {
+	if config.NoProgress || config.Output != outputText || os.Getenv("TERM") == "dumb" {
+		return nil
+	}
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		return nil
+	}
+	return &ProgressDisplay{term: os.Stdout}
+}
+
+// start shows the view for cloning total repositories. Until stop, standard output and the log
+// are redirected so they are printed above the view.
+func (d *ProgressDisplay) start(total int) {
+	if d == nil {
+		return
+	}
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		log.Printf("Warning: Live progress disabled: %v", err)
+		return
+	}
+
+	d.mu.Lock()
+	d.total, d.done, d.failed = total, 0, 0
+	d.started = time.Now()
+	d.active = nil
+	d.pipe = writer
+	d.closed = make(chan struct{})
+	d.quit = make(chan struct{})
+	d.drawLocked()
+	d.mu.Unlock()
+
+	os.Stdout = writer
+	log.SetOutput(writer)
+
+	go func() {
+		defer close(d.closed)
+		lines := bufio.NewReader(reader)
+		for {
+			line, err := lines.ReadString('\n')
+			if line != "" {
+				d.printAbove(line)
+			}
+			if err != nil {
+				reader.Close()
+				return
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(progressRedraw)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.mu.Lock()
+				d.drawLocked()
+				d.mu.Unlock()
+			case <-d.quit:
+				return
+			}
+		}
+	}()
+}
+
+// stop removes the view and restores standard output and the log
+func (d *ProgressDisplay) stop() {
+	if d == nil || d.pipe == nil {
+		return
+	}
+	close(d.quit)
+	os.Stdout = d.term
+	log.SetOutput(os.Stderr)
+	d.pipe.Close()
+	<-d.closed
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clearLocked()
+	d.pipe = nil
+}
+
+// begin adds a clone to the view and returns a context that sends git's progress output to it
+func (d *ProgressDisplay) begin(ctx context.Context, rec *RepoRecord) context.Context {
+	if d == nil || d.pipe == nil {
+		return ctx
+	}
+	clone := &cloneProgress{display: d, name: rec.Name, started: time.Now()}
+	d.mu.Lock()
+	d.active = append(d.active, clone)
+	d.drawLocked()
+	d.mu.Unlock()
+	return withGitProgress(ctx, clone)
+}
+
+// end removes a clone from the view and counts it as done, or failed by its outcome
+func (d *ProgressDisplay) end(rec *RepoRecord) {
+	if d == nil || d.pipe == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, clone := range d.active {
+		if clone.name == rec.Name {
+			d.active = append(d.active[:i], d.active[i+1:]...)
+			break
+		}
+	}
+	d.done++
+	if rec.Outcome == outcomeFailed || rec.Outcome == outcomeCanceled {
+		d.failed++
+	}
+	d.drawLocked()
+}
+
+// printAbove prints a line above the view
+func (d *ProgressDisplay) printAbove(line string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clearLocked()
+	if !strings.HasSuffix(line, "\n") {
+		line += "\n"
+	}
+	io.WriteString(d.term, line)
+	d.drawLocked()
+}
+
+// clearLocked moves the cursor up to the first line of the view and clears it
+func (d *ProgressDisplay) clearLocked() {
+	if d.drawn > 0 {
+		fmt.Fprintf(d.term, "\x1b[%dF\x1b[J", d.drawn)
+		d.drawn = 0
+	}
+}
+
+// drawLocked redraws the view
+func (d *ProgressDisplay) drawLocked() {
+	if d.pipe == nil {
+		return
+	}
+	elapsed := time.Since(d.started)
+
+	filled := 0
+	if d.total > 0 {
+		filled = d.done * progressBarWidth / d.total
+	}
+	overall := fmt.Sprintf("📦 [%s%s] %d/%d repositories",
+		strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled), d.done, d.total)
+	if d.failed > 0 {
+		overall += fmt.Sprintf(" · %d failed", d.failed)
+	}
+	overall += fmt.Sprintf(" · %s elapsed", elapsed.Round(time.Second))
+	if d.done > 0 && d.done < d.total {
+		eta := elapsed / time.Duration(d.done) * time.Duration(d.total-d.done)
+		overall += fmt.Sprintf(" · ETA %s", eta.Round(time.Second))
+	}
+
+	lines := []string{overall}
+	for _, clone := range d.active {
+		lines = append(lines, clone.statusLine())
+	}
+
+	width := 0
+	if w, _, err := term.GetSize(int(d.term.Fd())); err == nil {
+		width = w
+	}
+
+	d.clearLocked()
+	for _, line := range lines {
+		fmt.Fprintf(d.term, "%s\n", truncateLine(line, width))
+	}
+	d.drawn = len(lines)
+}
+
+// statusLine describes the progress of a clone
+func (c *cloneProgress) statusLine() string {
+	line := fmt.Sprintf("   ⬇️  %s", c.name)
+	if c.phase == "" {
+		line += " · starting"
+	} else {
+		line += fmt.Sprintf(" · %s %s%% (%s)", c.phase, c.percent, c.count)
+	}
+	if c.throughput != "" {
+		line += " · " + c.throughput
+	}
+	return line + fmt.Sprintf(" · %s", time.Since(c.started).Round(time.Second))
+}
+
+// Write parses git's progress output. git redraws progress lines with \r; errors and warnings
+// are printed above the view.
+func (c *cloneProgress) Write(p []byte) (int, error) {
+	d := c.display
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c.partial = append(c.partial, p...)
+	for {
+		i := strings.IndexAny(string(c.partial), "\r\n")
+		if i < 0 {
+			break
+		}
+		line := strings.TrimSpace(string(c.partial[:i]))
+		c.partial = c.partial[i+1:]
+
+		if m := gitProgressPattern.FindStringSubmatch(line); m != nil {
+			if m[1] != c.phase {
+				c.throughput = ""
+			}
+			c.phase, c.percent, c.count = m[1], m[2], m[3]
+			if t := gitThroughputPattern.FindStringSubmatch(line); t != nil {
+				c.throughput = t[1]
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "fatal:") || strings.HasPrefix(line, "error:") || strings.HasPrefix(line, "warning:") {
+			d.clearLocked()
+			fmt.Fprintf(d.term, "  %s: %s\n", c.name, line)
+		}
+	}
+	d.drawLocked()
+	return len(p), nil
+}
+
+// truncateLine shortens a line to the terminal width so it doesn't wrap, which would break redrawing
+func truncateLine(line string, width int) string {
+	if width <= 3 || utf8.RuneCountInString(line) < width-1 {
+		return line
+	}
+	// Leave room for emojis, which take two columns
+	runes := []rune(line)
+	return string(runes[:width-3]) + "…"
+}
+
+// gitProgressKey is the context key of the writer git's progress output is sent to
+type gitProgressKey struct{}
+
+// withGitProgress returns a context whose git commands send their progress output to w
+func withGitProgress(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, gitProgressKey{}, w)
+}
+
+// gitProgress returns the writer git's progress output is sent to, fallback unless the live view is shown
+func gitProgress(ctx context.Context, fallback io.Writer) io.Writer {
+	if w, ok := ctx.Value(gitProgressKey{}).(io.Writer); ok {
+		return w
+	}
+	return fallback
+}
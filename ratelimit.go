@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitReserve is how many requests are left unused before pausing until the rate limit resets
+const rateLimitReserve = 5
+
+// rateLimitResetBuffer is waited beyond the announced reset, to allow for clock differences
+const rateLimitResetBuffer = time.Second
+
+// errBudgetExhausted is returned for API requests beyond -api-budget
+var errBudgetExhausted = errors.New("API request budget exhausted")
+
+// RateTracker counts the API requests of a run, follows the rate limits reported by GitHub and
+// GitLab and pauses until a limit resets when its quota is nearly used up
+type RateTracker struct {
+	Budget int // Maximum API requests per run (0 for no limit)
+
+	mu     sync.Mutex
+	calls  int
+	limits map[string]*rateLimit // By resource: core, search, graphql (GitHub) or gitlab
+}
+
+// rateLimit is the last known state of a rate limit
+type rateLimit struct {
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+// newRateTracker returns a tracker allowing budget requests (0 for no limit)
+func newRateTracker(budget int) *RateTracker {
+	return &RateTracker{Budget: budget, limits: make(map[string]*rateLimit)}
+}
+
+// transport returns a RoundTripper sending requests through the tracker, or base itself for a nil tracker
+func (t *RateTracker) transport(base http.RoundTripper) http.RoundTripper {
+	if t == nil {
+		return base
+	}
+	return &rateTransport{tracker: t, base: base}
+}
+
+// Calls returns the number of API requests made so far
+func (t *RateTracker) Calls() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.calls
+}
+
+// before waits for the rate limit of the resource to reset if it is nearly used up, then counts the request.
+// Conditional GitHub requests only wait once the limit is exhausted, since a 304 answer is free.
+func (t *RateTracker) before(ctx context.Context, resource string, conditional bool) error {
+	t.mu.Lock()
+	if t.Budget > 0 && t.calls >= t.Budget {
+		t.mu.Unlock()
+		return fmt.Errorf("%w (%d requests)", errBudgetExhausted, t.Budget)
+	}
+	var wait *rateLimit
+	reserve := rateLimitReserve
+	if conditional && resource != "gitlab" {
+		reserve = 0
+	}
+	if limit, ok := t.limits[resource]; ok && limit.remaining <= reserve && time.Now().Before(limit.reset.Add(rateLimitResetBuffer)) {
+		copied := *limit
+		wait = &copied
+	}
+	t.mu.Unlock()
+
+	if wait != nil {
+		if err := waitForRateLimitReset(ctx, resource, *wait); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	t.calls++
+	if limit, ok := t.limits[resource]; ok && wait != nil {
+		// Assume the full quota is back until the next response says otherwise
+		limit.remaining = limit.limit
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+// refund uncounts a request answered with 304 Not Modified, which doesn't count against -api-budget
+func (t *RateTracker) refund() {
+	t.mu.Lock()
+	t.calls--
+	t.mu.Unlock()
+}
+
+// observe records the rate limit headers of a response: X-RateLimit-* on GitHub, RateLimit-* on GitLab
+func (t *RateTracker) observe(resource string, resp *http.Response) {
+	remaining, ok := rateHeader(resp.Header, "Remaining")
+	if !ok {
+		return
+	}
+	limit, _ := rateHeader(resp.Header, "Limit")
+	reset, _ := rateHeader(resp.Header, "Reset")
+	if r := resp.Header.Get("X-RateLimit-Resource"); r != "" {
+		resource = r
+	}
+
+	t.mu.Lock()
+	t.limits[resource] = &rateLimit{limit: limit, remaining: remaining, reset: time.Unix(int64(reset), 0)}
+	t.mu.Unlock()
+}
+
+// rateHeader reads a numeric X-RateLimit-<name> or RateLimit-<name> header
+func rateHeader(header http.Header, name string) (int, bool) {
+	value := header.Get("X-RateLimit-" + name)
+	if value == "" {
+		value = header.Get("RateLimit-" + name)
+	}
+	number, err := strconv.Atoi(value)
+	return number, err == nil
+}
+
+// displaySummary prints the API requests used and the last known rate limits
+func (t *RateTracker) displaySummary() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	budget := ""
+	if t.Budget > 0 {
+		budget = fmt.Sprintf(" of %d budgeted", t.Budget)
+	}
+	fmt.Printf("📡 API requests used: %d%s\n", t.calls, budget)
+
+	resources := make([]string, 0, len(t.limits))
+	for resource := range t.limits {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		limit := t.limits[resource]
+		fmt.Printf("   - %s rate limit: %d/%d remaining, resets at %s\n",
+			resource, limit.remaining, limit.limit, limit.reset.Local().Format("15:04:05"))
+	}
+}
+
+// waitForRateLimitReset sleeps until the rate limit resets, showing a countdown
+func waitForRateLimitReset(ctx context.Context, resource string, limit rateLimit) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	resumeAt := limit.reset.Add(rateLimitResetBuffer)
+	for {
+		left := time.Until(resumeAt)
+		if left <= 0 {
+			fmt.Printf("\r⏳ %s rate limit reset, resuming%s\n", resource, strings.Repeat(" ", 30))
+			return nil
+		}
+		fmt.Printf("\r⏳ %s rate limit nearly exhausted (%d/%d left), resuming in %s   ", resource, limit.remaining, limit.limit, left.Round(time.Second))
+
+		select {
+		case <-ctx.Done():
+			fmt.Println()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// rateResource returns the rate limit a request counts against, GitHub limits search and GraphQL separately
+func rateResource(req *http.Request) string {
+	switch {
+	case strings.HasPrefix(req.URL.Path, "/api/v4/"):
+		return "gitlab"
+	case strings.HasSuffix(req.URL.Path, "/graphql"):
+		return "graphql"
+	case strings.Contains(req.URL.Path, "/search/"):
+		return "search"
+	}
+	return "core"
+}
+
+// newAPIHTTPClient returns the HTTP client for platform API requests: responses are cached on disk
+// and revalidated (unless -no-cache), and requests are counted and throttled by the rate tracker.
+// The cache comes first so the tracker sees the conditional requests and their 304 answers.
+func newAPIHTTPClient(config Config) *http.Client {
+	transport := config.Rate.transport(http.DefaultTransport)
+	return &http.Client{Transport: config.Cache.transport(transport)}
+}
+
+// rateTransport sends requests through a RateTracker. A request rejected because the rate limit was
+// exhausted anyway (e.g. by another client sharing the token) is sent again once the limit resets.
+type rateTransport struct {
+	tracker *RateTracker
+	base    http.RoundTripper
+}
+
+func (rt *rateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource := rateResource(req)
+	// The API cache wraps this transport and adds the validators of cached responses
+	conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	for attempt := 0; ; attempt++ {
+		if err := rt.tracker.before(req.Context(), resource, conditional); err != nil {
+			return nil, err
+		}
+		resp, err := rt.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		rt.tracker.observe(resource, resp)
+		if resp.StatusCode == http.StatusNotModified {
+			rt.tracker.refund()
+		}
+
+		rateLimited := resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests
+		if remaining, ok := rateHeader(resp.Header, "Remaining"); !ok || remaining > 0 {
+			rateLimited = false
+		}
+		if !rateLimited || attempt > 0 || (req.Body != nil && req.GetBody == nil) {
+			return resp, nil
+		}
+
+		resp.Body.Close()
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAPICachePath(t *testing.T) {
+	cache := &APICache{Dir: t.TempDir()}
+	request := func(method, url string, header map[string]string) *http.Request {
+		req := httptest.NewRequest(method, url, nil)
+		for key, value := range header {
+			req.Header.Set(key, value)
+		}
+		return req
+	}
+	base := request(http.MethodGet, "https://api.github.com/orgs/acme/repos?page=1", map[string]string{"Authorization": "Bearer a"})
+
+	tests := []struct {
+		name     string
+		req      *http.Request
+		wantSame bool
+	}{
+		{name: "same request", req: request(http.MethodGet, "https://api.github.com/orgs/acme/repos?page=1", map[string]string{"Authorization": "Bearer a"}), wantSame: true},
+		{name: "other page", req: request(http.MethodGet, "https://api.github.com/orgs/acme/repos?page=2", map[string]string{"Authorization": "Bearer a"})},
+		{name: "other method", req: request(http.MethodHead, "https://api.github.com/orgs/acme/repos?page=1", map[string]string{"Authorization": "Bearer a"})},
+		{name: "other token", req: request(http.MethodGet, "https://api.github.com/orgs/acme/repos?page=1", map[string]string{"Authorization": "Bearer b"})},
+		{name: "other Accept", req: request(http.MethodGet, "https://api.github.com/orgs/acme/repos?page=1", map[string]string{"Authorization": "Bearer a", "Accept": "application/vnd.github.raw"})},
+		{name: "GitLab token", req: request(http.MethodGet, "https://api.github.com/orgs/acme/repos?page=1", map[string]string{"Authorization": "Bearer a", "Private-Token": "glpat"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := cache.path(tt.req) == cache.path(base); same != tt.wantSame {
+				t.Errorf("same cache file = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+// newRevalidatingServer returns a server answering every request with an ETag, and with 304 Not
+// Modified when the request has a matching If-None-Match. Rate limit headers report remaining requests.
+func newRevalidatingServer(t *testing.T, remaining int) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, `[{"name":"api"}]`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestAPIHTTPClientRevalidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		remaining int
+		budget    int
+	}{
+		{name: "304s don't count against the budget", remaining: 4000, budget: 2},
+		{name: "304s don't wait for the rate limit reserve", remaining: rateLimitReserve},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newRevalidatingServer(t, tt.remaining)
+			config := Config{Rate: newRateTracker(tt.budget), Cache: &APICache{Dir: t.TempDir()}}
+			client := newAPIHTTPClient(config)
+
+			// A wait for the rate limit reset would exceed the timeout
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			for i := 0; i < 4; i++ {
+				req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/orgs/acme/repos", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("request %d: %v", i+1, err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK || string(body) != `[{"name":"api"}]` {
+					t.Fatalf("request %d = %d %s, want the cached listing", i+1, resp.StatusCode, body)
+				}
+				if fromCache := resp.Header.Get("X-From-Cache") != ""; fromCache != (i > 0) {
+					t.Errorf("request %d served from cache = %v", i+1, fromCache)
+				}
+			}
+
+			if *requests != 4 {
+				t.Errorf("server got %d requests, want 4", *requests)
+			}
+			if calls := config.Rate.Calls(); calls != 1 {
+				t.Errorf("Calls() = %d, want 1 (the 304s are free)", calls)
+			}
+			if config.Cache.revalidated != 3 || config.Cache.stored != 1 {
+				t.Errorf("cache revalidated %d and stored %d responses, want 3 and 1", config.Cache.revalidated, config.Cache.stored)
+			}
+		})
+	}
+}
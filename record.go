@@ -0,0 +1,62 @@
+package main
+
+// Outcomes recorded for each discovered repository
+const (
+	outcomeCloned  = "cloned"  // Repository was cloned during this run
+	outcomeUpdated = "updated" // Existing local repository was updated from the remote
+	outcomeExists  = "exists"  // Repository already existed locally and was left untouched
+	outcomeFailed  = "failed"  // Cloning the repository failed
+	outcomeSkipped = "skipped" // Repository was filtered out and not cloned
+
+	outcomeCanceled   = "canceled"    // Clone was in progress when the run was interrupted or timed out
+	outcomeNotStarted = "not_started" // Run was interrupted or timed out before the repository was processed
+
+	outcomePlannedClone  = "would_clone"  // -dry-run: repository would be cloned
+	outcomePlannedUpdate = "would_update" // -dry-run: existing local repository would be updated
+)
+
+// RepoRecord describes a discovered repository and what happened to it during the run
+type RepoRecord struct {
+	Platform      string       // github or gitlab
+	ID            int64        // Repository (GitHub) or project (GitLab) ID
+	Namespace     string       // Owning organization or group path
+	Name          string       // Repository name, also used as the local directory name
+	WebURL        string       // Browser URL of the repository
+	HTTPSURL      string       // HTTPS clone URL
+	SSHURL        string       // SSH clone URL
+	DefaultBranch string       // Default branch reported by the platform
+	Visibility    string       // public, internal or private
+	Archived      bool         // Whether the repository is archived
+	LocalPath     string       // Path of the local clone
+	Outcome       string       // One of the outcome* constants
+	Reason        string       // Why the repository was skipped, or the clone error
+	Catalog       *CatalogYAML // Parsed .catalog.yml, if known
+	CatalogError  string       // Error fetching or parsing .catalog.yml, if any
+	HasSubmodules bool         // Whether the repository has a .gitmodules file
+	UsesLFS       bool         // Whether the repository tracks files with Git LFS
+	BrokenHead    bool         // Whether the local clone's HEAD didn't resolve to a commit
+}
+
+// CloneURL returns the URL to clone the repository with
+func (r *RepoRecord) CloneURL(useSSH bool) string {
+	if useSSH {
+		return r.SSHURL
+	}
+	return r.HTTPSURL
+}
+
+// isProductionCatalog reports whether a catalog declares component.lifecycle: production
+func isProductionCatalog(catalog *CatalogYAML) bool {
+	return catalog != nil && catalog.Component.Lifecycle == "production"
+}
+
+// countOutcome returns the number of records with the given outcome
+func countOutcome(records []*RepoRecord, outcome string) int {
+	count := 0
+	for _, rec := range records {
+		if rec.Outcome == outcome {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Exit codes of the report subcommands
+const (
+	exitOK       = 0 // Report generated, nothing to flag
+	exitFindings = 1 // Report generated with findings (for CI)
+	exitError    = 2 // Invalid flags or the report could not be generated
+)
+
+// reportCommands are the reports of the report command
+var reportCommands = map[string]func([]string) int{
+	"kafka-graph": runKafkaGraph,
+	"kafka-check": runKafkaCheck,
+	"owners":      runOwners,
+}
+
+// runReport implements the report command, dispatching to the report given as first argument
+func runReport(args []string) int {
+	if len(args) > 0 {
+		if run, ok := reportCommands[args[0]]; ok {
+			return run(args[1:])
+		}
+	}
+
+	w := os.Stderr
+	code := exitError
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+		w, code = os.Stdout, exitOK
+	} else if len(args) > 0 {
+		fmt.Fprintf(w, "Error: unknown report '%s'\n\n", args[0])
+	}
+	fmt.Fprintf(w, "Usage: git-repo-downloader report <report> [flags]\n\n")
+	fmt.Fprintf(w, "Generates reports from the .catalog.yml files of already downloaded repositories.\n\n")
+	fmt.Fprintf(w, "Reports:\n")
+	fmt.Fprintf(w, "  kafka-graph  Producer → topic → consumer graph as DOT, Mermaid or JSON\n")
+	fmt.Fprintf(w, "  kafka-check  Kafka topic and consumer group consistency checks\n")
+	fmt.Fprintf(w, "  owners       Services per team and CODEOWNERS mismatches\n\n")
+	fmt.Fprintf(w, "Run 'git-repo-downloader report <report> -h' for the flags of a report.\n")
+	fmt.Fprintf(w, "Exit codes: 0 on success, 1 when a report has findings (for CI), 2 on invalid flags and errors.\n")
+	return code
+}
+
+// loadCatalogs scans a directory of downloaded repositories for reports, warning about invalid catalogs
+func loadCatalogs(dir string) ([]CatalogInfo, error) {
+	dir, err := expandHomeDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error getting home directory: %w", err)
+	}
+
+	catalogs, err := scanForCatalogFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range catalogs {
+		if info.ParseError != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Skipping invalid catalog %s: %v\n", info.CatalogPath, info.ParseError)
+		}
+	}
+	return catalogs, nil
+}
+
+// writeReport writes a report to the given file, or to stdout when path is empty
+func writeReport(path string, write func(io.Writer) error) error {
+	if path == "" {
+		return write(os.Stdout)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer file.Close()
+
+	if err := write(file); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return file.Close()
+}
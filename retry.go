@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/xanzy/go-gitlab"
+)
+
+// maxRetryDelay caps the exponential backoff between two attempts
+const maxRetryDelay = time.Minute
+
+// RetryPolicy controls how often and how long transient failures are retried
+type RetryPolicy struct {
+	Retries   int           // Retries after the first attempt (0 disables retrying)
+	BaseDelay time.Duration // Delay before the first retry, doubled for every further retry
+}
+
+// backoff returns the jittered delay before retry number attempt (starting at 0): the exponential
+// delay is capped at maxRetryDelay and then randomized between half and all of it
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// retryClassifier reports whether an error is transient, and how long the server asked to wait
+// before retrying (0 to use the policy's backoff)
+type retryClassifier func(err error) (retryable bool, wait time.Duration)
+
+// withRetry calls fn until it succeeds, fails permanently, the retries are used up or ctx ends
+func withRetry(ctx context.Context, policy RetryPolicy, what string, classify retryClassifier, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || ctx.Err() != nil || attempt >= policy.Retries {
+			return err
+		}
+		retryable, wait := classify(err)
+		if !retryable {
+			return err
+		}
+		if wait <= 0 {
+			wait = policy.backoff(attempt)
+		}
+
+		log.Printf("Warning: %s failed (attempt %d/%d): %v; retrying in %s", what, attempt+1, policy.Retries+1, err, wait.Round(100*time.Millisecond))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAPI retries a GitHub or GitLab API call on transient failures
+func retryAPI(ctx context.Context, policy RetryPolicy, what string, fn func() error) error {
+	return withRetry(ctx, policy, what, classifyAPIError, fn)
+}
+
+// classifyAPIError treats network errors, 429 and 5xx responses and GitHub secondary rate limits
+// as transient, honoring Retry-After. Other errors, such as 404 or 401, are permanent.
+func classifyAPIError(err error) (bool, time.Duration) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errBudgetExhausted) {
+		return false, 0
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		return true, abuseErr.GetRetryAfter()
+	}
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		// Only happens when the quota was used up by another client sharing the token,
+		// the rate tracker normally pauses before the limit is reached
+		return true, time.Until(rateErr.Rate.Reset.Time) + rateLimitResetBuffer
+	}
+
+	var resp *http.Response
+	var githubErr *github.ErrorResponse
+	var gitlabErr *gitlab.ErrorResponse
+	var graphQLErr *GraphQLError
+	switch {
+	case errors.As(err, &githubErr):
+		resp = githubErr.Response
+	case errors.As(err, &gitlabErr):
+		resp = gitlabErr.Response
+	case errors.As(err, &graphQLErr):
+		if graphQLErr.Response == nil {
+			// Errors in the response body, such as a missing field, don't go away by retrying
+			return false, 0
+		}
+		resp = graphQLErr.Response
+	}
+	if resp != nil {
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			return true, retryAfter
+		case resp.StatusCode == http.StatusForbidden && retryAfter > 0:
+			// Secondary rate limits of APIs without a dedicated error type
+			return true, retryAfter
+		}
+		return false, 0
+	}
+
+	return isNetworkError(err), 0
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// isNetworkError reports whether err is a connection-level failure worth retrying
+func isNetworkError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		isTransientMessage(err.Error())
+}
+
+// transientGitMessages are fragments of git and go-git errors caused by network or server problems
+var transientGitMessages = []string{
+	"could not resolve host",
+	"connection timed out",
+	"connection reset",
+	"connection refused",
+	"operation timed out",
+	"failed to connect",
+	"the remote end hung up unexpectedly",
+	"unexpected disconnect",
+	"early eof",
+	"rpc failed",
+	"tls handshake timeout",
+	"gnutls recv error",
+	"returned error: 429",
+	"returned error: 500",
+	"returned error: 502",
+	"returned error: 503",
+	"returned error: 504",
+	"internal server error",
+	"bad gateway",
+	"service unavailable",
+	"gateway timeout",
+}
+
+// isTransientMessage reports whether an error message points at a network or server problem
+func isTransientMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, fragment := range transientGitMessages {
+		if strings.Contains(message, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyCloneError treats clone failures caused by the network or the server as transient.
+// Authentication failures, missing repositories, timeouts and local errors are permanent.
+func classifyCloneError(err error) (bool, time.Duration) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, 0
+	}
+	var gitErr *GitError
+	if errors.As(err, &gitErr) {
+		return isTransientMessage(gitErr.Stderr), 0
+	}
+	return isNetworkError(err), 0
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v66/github"
+	"github.com/xanzy/go-gitlab"
+)
+
+// httpResponse returns a response with the given status and Retry-After header for API error types
+func httpResponse(status int, retryAfter string) *http.Response {
+	header := http.Header{}
+	if retryAfter != "" {
+		header.Set("Retry-After", retryAfter)
+	}
+	return &http.Response{StatusCode: status, Header: header}
+}
+
+func TestClassifyAPIError(t *testing.T) {
+	retryAfter := 42 * time.Second
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+		wait      time.Duration
+	}{
+		{name: "canceled", err: context.Canceled},
+		{name: "deadline exceeded", err: fmt.Errorf("listing: %w", context.DeadlineExceeded)},
+		{name: "budget exhausted", err: fmt.Errorf("listing: %w", errBudgetExhausted)},
+		{name: "secondary rate limit", err: &github.AbuseRateLimitError{RetryAfter: &retryAfter}, retryable: true, wait: retryAfter},
+		{name: "GitHub 502", err: &github.ErrorResponse{Response: httpResponse(http.StatusBadGateway, "")}, retryable: true},
+		{name: "GitHub 429 with Retry-After", err: &github.ErrorResponse{Response: httpResponse(http.StatusTooManyRequests, "30")}, retryable: true, wait: 30 * time.Second},
+		{name: "GitHub 403 with Retry-After", err: &github.ErrorResponse{Response: httpResponse(http.StatusForbidden, "5")}, retryable: true, wait: 5 * time.Second},
+		{name: "GitHub 403", err: &github.ErrorResponse{Response: httpResponse(http.StatusForbidden, "")}},
+		{name: "GitHub 404", err: &github.ErrorResponse{Response: httpResponse(http.StatusNotFound, "")}},
+		{name: "GitLab 503", err: fmt.Errorf("listing projects: %w", &gitlab.ErrorResponse{Response: httpResponse(http.StatusServiceUnavailable, "")}), retryable: true},
+		{name: "GitLab 401", err: &gitlab.ErrorResponse{Response: httpResponse(http.StatusUnauthorized, "")}},
+		{name: "GraphQL 500", err: &GraphQLError{Response: httpResponse(http.StatusInternalServerError, "")}, retryable: true},
+		{name: "GraphQL errors in the body", err: &GraphQLError{Messages: []string{"Field 'blob' doesn't exist"}}},
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, retryable: true},
+		{name: "unexpected EOF", err: fmt.Errorf("reading response: %w", io.ErrUnexpectedEOF), retryable: true},
+		{name: "transient message", err: errors.New("Get \"https://api.github.com\": tls handshake timeout"), retryable: true},
+		{name: "other error", err: errors.New("invalid character '<' looking for beginning of value")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryable, wait := classifyAPIError(tt.err)
+			if retryable != tt.retryable || wait != tt.wait {
+				t.Errorf("classifyAPIError() = %v, %s, want %v, %s", retryable, wait, tt.retryable, tt.wait)
+			}
+		})
+	}
+}
+
+func TestClassifyAPIErrorRateLimit(t *testing.T) {
+	err := &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(time.Minute)}}}
+	retryable, wait := classifyAPIError(err)
+	if !retryable {
+		t.Fatal("classifyAPIError() didn't retry an exhausted rate limit")
+	}
+	if wait < 59*time.Second || wait > time.Minute+rateLimitResetBuffer {
+		t.Errorf("classifyAPIError() waits %s, want until the rate limit resets", wait)
+	}
+}
+
+func TestClassifyCloneError(t *testing.T) {
+	exitErr := errors.New("exit status 128")
+
+	tests := []struct {
+		name      string
+		err       error
+		retryable bool
+	}{
+		{name: "canceled", err: context.Canceled},
+		{name: "timed out", err: fmt.Errorf("git clone failed: %w", context.DeadlineExceeded)},
+		{
+			name:      "connection reset",
+			err:       &GitError{Err: exitErr, Stderr: "Cloning into 'api'...\nerror: RPC failed; curl 56 Recv failure: Connection reset by peer\nfatal: early EOF\n"},
+			retryable: true,
+		},
+		{
+			name:      "could not resolve host",
+			err:       fmt.Errorf("git clone failed: %w", &GitError{Err: exitErr, Stderr: "fatal: unable to access 'https://github.com/acme/api.git/': Could not resolve host: github.com\n"}),
+			retryable: true,
+		},
+		{
+			name:      "server error",
+			err:       &GitError{Err: exitErr, Stderr: "fatal: unable to access 'https://gitlab.com/acme/api.git/': The requested URL returned error: 502\n"},
+			retryable: true,
+		},
+		{
+			name: "repository not found",
+			err:  &GitError{Err: exitErr, Stderr: "remote: Repository not found.\nfatal: repository 'https://github.com/acme/api.git/' not found\n"},
+		},
+		{
+			name: "authentication failed",
+			err:  &GitError{Err: exitErr, Stderr: "fatal: Authentication failed for 'https://github.com/acme/api.git/'\n"},
+		},
+		{
+			// Only git's own output decides, not the wrapping error message
+			name: "network words outside stderr",
+			err:  fmt.Errorf("connection reset: %w", &GitError{Err: exitErr, Stderr: "fatal: destination path 'api' already exists\n"}),
+		},
+		{name: "go-git network error", err: fmt.Errorf("clone: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("i/o timeout")}), retryable: true},
+		{name: "go-git unexpected EOF", err: io.ErrUnexpectedEOF, retryable: true},
+		{name: "local error", err: errors.New("failed to move clone into place: permission denied")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retryable, wait := classifyCloneError(tt.err)
+			if retryable != tt.retryable || wait != 0 {
+				t.Errorf("classifyCloneError() = %v, %s, want %v, 0s", retryable, wait, tt.retryable)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ScanReport is the JSON output of the scan command
+type ScanReport struct {
+	Repositories []ScannedRepo `json:"repositories"`
+	WithCatalog  int           `json:"with_catalog"`
+	Invalid      int           `json:"invalid"`
+	Missing      int           `json:"missing"`
+}
+
+// ScannedRepo is the catalog state of a single downloaded repository
+type ScannedRepo struct {
+	Repository  string   `json:"repository"`
+	Path        string   `json:"path"`
+	CatalogPath string   `json:"catalog_path"`
+	HasCatalog  bool     `json:"has_catalog"`
+	Error       string   `json:"error,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Name        string   `json:"name,omitempty"`
+	Service     string   `json:"service,omitempty"`
+	Team        string   `json:"team,omitempty"`
+	Lifecycle   string   `json:"lifecycle,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// runScan implements the scan command: the catalog scan of an already downloaded directory
+func runScan(args []string) int {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	dir := fs.String("dir", "./repositories", "Directory containing the downloaded repositories")
+	format := fs.String("format", "text", "Output format: text or json")
+	out := fs.String("out", "", "Write the results to this file instead of stdout")
+	requireCatalog := fs.Bool("require-catalog", false, "Also report repositories missing .catalog.yml as findings")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: git-repo-downloader scan [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Scans the .catalog.yml files of already downloaded repositories, without network access.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nExit codes: 0 when all catalogs are valid, 1 on invalid catalogs (or missing ones with\n")
+		fmt.Fprintf(fs.Output(), "-require-catalog), 2 on invalid flags and errors.\n")
+	}
+	fs.Parse(args)
+
+	var render func(io.Writer, []CatalogInfo) error
+	switch strings.ToLower(*format) {
+	case "text":
+		render = renderScanText
+	case "json":
+		render = renderScanJSON
+	default:
+		fmt.Fprintf(os.Stderr, "Error: invalid format '%s'. Must be 'text' or 'json'\n", *format)
+		return exitError
+	}
+
+	targetDir, err := expandHomeDir(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: error getting home directory: %v\n", err)
+		return exitError
+	}
+	catalogs, err := scanForCatalogFiles(targetDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	if err := writeReport(*out, func(w io.Writer) error { return render(w, catalogs) }); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	if *out != "" {
+		fmt.Printf("📄 Catalog scan of %d repositories written to: %s\n", len(catalogs), *out)
+	}
+
+	report := newScanReport(catalogs)
+	if report.Invalid > 0 || (*requireCatalog && report.Missing > 0) {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// newScanReport converts scanned catalogs into the JSON report
+func newScanReport(catalogs []CatalogInfo) ScanReport {
+	report := ScanReport{Repositories: []ScannedRepo{}}
+	for _, info := range catalogs {
+		repo := ScannedRepo{
+			Repository:  info.RepoName,
+			Path:        info.RepoPath,
+			CatalogPath: info.CatalogPath,
+			HasCatalog:  info.HasCatalog,
+		}
+		switch {
+		case !info.HasCatalog:
+			report.Missing++
+		case info.ParseError != nil:
+			repo.Error = info.ParseError.Error()
+			report.WithCatalog++
+			report.Invalid++
+		default:
+			c := info.Catalog.Component
+			repo.Type = info.Catalog.Type
+			repo.Name = c.Name
+			repo.Service = c.Service
+			repo.Team = c.Team
+			repo.Lifecycle = c.Lifecycle
+			repo.Tags = c.Tags
+			report.WithCatalog++
+		}
+		report.Repositories = append(report.Repositories, repo)
+	}
+	return report
+}
+
+// renderScanText writes the scan results in the format of the --prod final scan
+func renderScanText(w io.Writer, catalogs []CatalogInfo) error {
+	writeCatalogResults(w, catalogs)
+	return nil
+}
+
+// renderScanJSON writes the scan results as indented JSON
+func renderScanJSON(w io.Writer, catalogs []CatalogInfo) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(newScanReport(catalogs))
+}
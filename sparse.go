@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SparseConfig holds sparse-checkout patterns, globally and per catalog type.
+// Patterns use gitignore syntax (git sparse-checkout non-cone mode), e.g.
+//
+//	default:
+//	  - /.catalog.yml
+//	types:
+//	  microservice:
+//	    - /.catalog.yml
+//	    - /go.mod
+//	    - /Dockerfile
+//	    - /.github/
+type SparseConfig struct {
+	Default []string            `yaml:"default"`
+	Types   map[string][]string `yaml:"types"`
+}
+
+// loadSparseConfig reads the sparse-checkout configuration file, if any, and applies the
+// comma-separated -sparse patterns, which take precedence over the file's default patterns
+func loadSparseConfig(path, patterns string) (SparseConfig, error) {
+	var sparse SparseConfig
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return sparse, fmt.Errorf("failed to read sparse config: %w", err)
+		}
+		if err := yaml.Unmarshal(content, &sparse); err != nil {
+			return sparse, fmt.Errorf("failed to parse sparse config: %w", err)
+		}
+	}
+
+	if patterns != "" {
+		sparse.Default = nil
+		for _, pattern := range strings.Split(patterns, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				sparse.Default = append(sparse.Default, pattern)
+			}
+		}
+	}
+	return sparse, nil
+}
+
+// enabled reports whether any sparse-checkout patterns are configured
+func (s SparseConfig) enabled() bool {
+	return len(s.Default) > 0 || len(s.Types) > 0
+}
+
+// patternsFor returns the patterns for a catalog type, falling back to the default patterns.
+// An empty result means the full working tree is checked out.
+func (s SparseConfig) patternsFor(catalogType string) []string {
+	if patterns, ok := s.Types[catalogType]; ok && catalogType != "" {
+		return patterns
+	}
+	return s.Default
+}
+
+// sparseClone clones without checking out, picks the sparse patterns from the catalog type and then
+// checks out only the matching files. Unless another filter is set, the clone is partial (blob:none)
+// so only the blobs of the sparse working tree are downloaded.
+func sparseClone(ctx context.Context, config Config, rec *RepoRecord, cloneURL, repoPath string) error {
+	if config.Filter == "" {
+		config.Filter = "blob:none"
+	}
+	args := cloneArgs(config, cloneURL, repoPath)
+	args = append(args[:1], append([]string{"--no-checkout"}, args[1:]...)...)
+
+	if err := runGit(ctx, "", lfsEnv(config), args...); err != nil {
+		return fmt.Errorf("git clone failed: %w", err)
+	}
+
+	// The catalog is already known in production mode, otherwise read it from the fetched HEAD
+	catalog := rec.Catalog
+	if catalog == nil && len(config.Sparse.Types) > 0 {
+		if _, headCatalog, err := readCatalogFromHead(repoPath); err == nil {
+			catalog = headCatalog
+		}
+	}
+	catalogType := ""
+	if catalog != nil {
+		catalogType = catalog.Type
+	}
+
+	patterns := config.Sparse.patternsFor(catalogType)
+	if len(patterns) > 0 {
+		fmt.Printf("  Sparse checkout (type: %s): %s\n", orDash(catalogType), strings.Join(patterns, " "))
+		setArgs := append([]string{"sparse-checkout", "set", "--no-cone"}, patterns...)
+		if _, err := gitOutputContext(ctx, repoPath, nil, setArgs...); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("  No sparse patterns for type %s, checking out the full working tree\n", orDash(catalogType))
+	}
+
+	if _, err := gitOutputContext(ctx, repoPath, lfsEnv(config), "checkout"); err != nil {
+		return err
+	}
+	return nil
+}
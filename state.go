@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// stateFileName is the name of the sync state file in the target directory
+const stateFileName = ".git-repo-downloader-state.yaml"
+
+// stateVersion is the format version of the sync state file
+const stateVersion = 1
+
+// SyncState records what was downloaded when, kept in the target directory across runs. Entries are
+// sorted so the file diffs cleanly in version control.
+type SyncState struct {
+	Version      int         `yaml:"version"`
+	Repositories []RepoState `yaml:"repositories"`
+}
+
+// RepoState is the last synced state of a single repository. LastSynced is when the repository was
+// last synced, HeadSince when HeadSHA was first recorded.
+type RepoState struct {
+	Platform      string            `yaml:"platform"`
+	ID            int64             `yaml:"id"`
+	Namespace     string            `yaml:"namespace"`
+	Name          string            `yaml:"name"`
+	PreviousNames []string          `yaml:"previous_names,omitempty"` // Earlier namespace/name of renamed or moved repositories
+	WebURL        string            `yaml:"web_url"`
+	HTTPSURL      string            `yaml:"https_url"`
+	SSHURL        string            `yaml:"ssh_url"`
+	DefaultBranch string            `yaml:"default_branch"`
+	LocalPath     string            `yaml:"local_path"`
+	HeadRef       string            `yaml:"head_ref"`
+	HeadSHA       string            `yaml:"head_sha"`
+	Catalog       *InventoryCatalog `yaml:"catalog,omitempty"`
+	HeadSince     time.Time         `yaml:"head_since"`
+	LastSynced    time.Time         `yaml:"last_synced"`
+}
+
+// stateKey identifies a repository across renames by its platform and remote ID
+type stateKey struct {
+	platform string
+	id       int64
+}
+
+// statePath returns the path of the sync state file of a target directory
+func statePath(targetDir string) string {
+	return filepath.Join(targetDir, stateFileName)
+}
+
+// readSyncState reads the sync state file, returning an empty state if there is none yet
+func readSyncState(path string) (SyncState, error) {
+	state := SyncState{Version: stateVersion}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	if err := yaml.Unmarshal(content, &state); err != nil {
+		return state, fmt.Errorf("failed to parse sync state %s: %w", path, err)
+	}
+	if state.Version > stateVersion {
+		return state, fmt.Errorf("sync state %s has version %d, this version of git-repo-downloader only supports %d", path, state.Version, stateVersion)
+	}
+	return state, nil
+}
+
+// writeSyncState writes the sync state file, replacing the previous one only once it is complete
+func writeSyncState(path string, state SyncState) error {
+	sort.Slice(state.Repositories, func(i, j int) bool {
+		a, b := state.Repositories[i], state.Repositories[j]
+		if a.Platform != b.Platform {
+			return a.Platform < b.Platform
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	var content bytes.Buffer
+	content.WriteString("# Sync state of git-repo-downloader, updated on every run. Safe to commit.\n")
+	encoder := yaml.NewEncoder(&content)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(state); err != nil {
+		return fmt.Errorf("failed to encode sync state: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode sync state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".state-*")
+	if err != nil {
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	if _, err := tmp.Write(content.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write sync state: %w", err)
+	}
+	return nil
+}
+
+// localHead returns the checked-out branch and commit SHA of a local repository. It uses go-git so
+// it also works without a git binary.
+func localHead(repoPath string) (ref, sha string, err error) {
+	repo, err := git.PlainOpen(repoPath)
+	if err != nil {
+		return "", "", err
+	}
+	head, err := repo.Head()
+	if err != nil {
+		return "", "", err
+	}
+	return head.Name().Short(), head.Hash().String(), nil
+}
+
+// updateSyncState records the repositories that were cloned, updated or already present in the
+// state file of the target directory. Repositories that keep their remote ID but changed name or
+// namespace are reported as renamed.
+func updateSyncState(config Config, records []*RepoRecord) error {
+	path := statePath(config.TargetDir)
+	state, err := readSyncState(path)
+	if err != nil {
+		return err
+	}
+
+	index := make(map[stateKey]int, len(state.Repositories))
+	for i, repo := range state.Repositories {
+		index[stateKey{repo.Platform, repo.ID}] = i
+	}
+
+	fillLocalCatalogs(records)
+	now := time.Now().UTC().Truncate(time.Second)
+	added, moved, renamed := 0, 0, 0
+	for _, rec := range records {
+		if rec.Outcome != outcomeCloned && rec.Outcome != outcomeUpdated && rec.Outcome != outcomeExists {
+			continue
+		}
+		ref, sha, err := localHead(rec.LocalPath)
+		if err != nil {
+			fmt.Printf("⚠️  %s: can't read HEAD for the sync state: %v\n", rec.Name, err)
+			continue
+		}
+
+		entry := RepoState{
+			Platform:      rec.Platform,
+			ID:            rec.ID,
+			Namespace:     rec.Namespace,
+			Name:          rec.Name,
+			WebURL:        rec.WebURL,
+			HTTPSURL:      rec.HTTPSURL,
+			SSHURL:        rec.SSHURL,
+			DefaultBranch: rec.DefaultBranch,
+			LocalPath:     rec.LocalPath,
+			HeadRef:       ref,
+			HeadSHA:       sha,
+			Catalog:       newInventoryCatalog(rec.Catalog),
+			HeadSince:     now,
+			LastSynced:    now,
+		}
+
+		i, known := index[stateKey{rec.Platform, rec.ID}]
+		if !known {
+			index[stateKey{rec.Platform, rec.ID}] = len(state.Repositories)
+			state.Repositories = append(state.Repositories, entry)
+			added++
+			continue
+		}
+
+		previous := state.Repositories[i]
+		entry.PreviousNames = previous.PreviousNames
+		if previous.Namespace != rec.Namespace || previous.Name != rec.Name {
+			oldName := previous.Namespace + "/" + previous.Name
+			fmt.Printf("🔀 %s was renamed or moved to %s/%s\n", oldName, rec.Namespace, rec.Name)
+			if previous.LocalPath != rec.LocalPath {
+				fmt.Printf("   The clone at %s is no longer synced and can be removed\n", previous.LocalPath)
+			}
+			entry.PreviousNames = append(entry.PreviousNames, oldName)
+			renamed++
+		}
+		switch {
+		case previous.HeadSHA != sha:
+			moved++
+		case previous.HeadSince.IsZero():
+			// State files written before head_since existed recorded it as last_synced
+			entry.HeadSince = previous.LastSynced
+		default:
+			entry.HeadSince = previous.HeadSince
+		}
+		state.Repositories[i] = entry
+	}
+
+	if err := writeSyncState(path, state); err != nil {
+		return err
+	}
+	fmt.Printf("📌 Sync state of %d repositories written to: %s (%d new, %d at a new commit, %d renamed)\n",
+		len(state.Repositories), path, added, moved, renamed)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUpdateSyncStateTimestamps(t *testing.T) {
+	setTestGitIdentity(t)
+	remote := newTestRemote(t, map[string]string{"README.md": "# api\n"})
+	earlier := time.Date(2024, 5, 2, 8, 15, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		previous      *RepoState
+		wantHeadSince time.Time // Zero for the time of this sync
+	}{
+		{name: "new repository"},
+		{name: "same commit", previous: &RepoState{HeadSince: earlier, LastSynced: earlier.Add(time.Hour)}, wantHeadSince: earlier},
+		{name: "new commit", previous: &RepoState{HeadSHA: "83e0b31c1f436ca6189325eae989ecd75dadf209", HeadSince: earlier, LastSynced: earlier}},
+		{name: "state written before head_since", previous: &RepoState{LastSynced: earlier}, wantHeadSince: earlier},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			repoPath := filepath.Join(targetDir, "api")
+			runTestGit(t, "", "clone", "--quiet", remote, repoPath)
+			sha := runTestGit(t, repoPath, "rev-parse", "HEAD")
+
+			if tt.previous != nil {
+				previous := *tt.previous
+				previous.Platform, previous.ID, previous.Namespace, previous.Name = "github", 1, "acme", "api"
+				if previous.HeadSHA == "" {
+					previous.HeadSHA = sha
+				}
+				if err := writeSyncState(statePath(targetDir), SyncState{Version: stateVersion, Repositories: []RepoState{previous}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			records := []*RepoRecord{{Platform: "github", ID: 1, Namespace: "acme", Name: "api", LocalPath: repoPath, Outcome: outcomeExists}}
+			before := time.Now().UTC().Truncate(time.Second)
+			if err := updateSyncState(Config{TargetDir: targetDir}, records); err != nil {
+				t.Fatalf("updateSyncState() error = %v", err)
+			}
+
+			state, err := readSyncState(statePath(targetDir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(state.Repositories) != 1 {
+				t.Fatalf("state has %d repositories, want 1", len(state.Repositories))
+			}
+			repo := state.Repositories[0]
+			if repo.HeadSHA != sha {
+				t.Errorf("HeadSHA = %s, want %s", repo.HeadSHA, sha)
+			}
+			if repo.LastSynced.Before(before) {
+				t.Errorf("LastSynced = %s, want the time of this sync", repo.LastSynced)
+			}
+			want := tt.wantHeadSince
+			if want.IsZero() {
+				want = repo.LastSynced
+			}
+			if !repo.HeadSince.Equal(want) {
+				t.Errorf("HeadSince = %s, want %s", repo.HeadSince, want)
+			}
+		})
+	}
+}
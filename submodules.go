@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// detectRepoFeatures records whether a cloned repository uses submodules or Git LFS, based on
+// the .gitmodules and root .gitattributes committed at HEAD (this also works for mirrors)
+func detectRepoFeatures(rec *RepoRecord, repoPath string) {
+	if _, found, _ := readFileFromHead(repoPath, ".gitmodules"); found {
+		rec.HasSubmodules = true
+	}
+	if attributes, _, err := readFileFromHead(repoPath, ".gitattributes"); err == nil {
+		rec.UsesLFS = strings.Contains(string(attributes), "filter=lfs")
+	}
+}
+
+// submoduleURLRewrites returns git -c options rewriting submodule URLs on the clone host to the
+// protocol selected with -ssh, so submodules are fetched with the same credentials as the repository
+func submoduleURLRewrites(cloneURL string, useSSH bool) []string {
+	host, _, err := parseRemoteURL(cloneURL)
+	if err != nil {
+		return nil
+	}
+
+	https := "https://" + host + "/"
+	scp := "git@" + host + ":"
+	ssh := "ssh://git@" + host + "/"
+	if useSSH {
+		return []string{"-c", "url." + scp + ".insteadOf=" + https}
+	}
+	return []string{
+		"-c", "url." + https + ".insteadOf=" + scp,
+		"-c", "url." + https + ".insteadOf=" + ssh,
+	}
+}
+
+// completeClone detects the features of a fresh or updated clone at repoPath and initializes its
+// submodules and LFS objects as configured
+func completeClone(ctx context.Context, config Config, rec *RepoRecord, repoPath string) error {
+	detectRepoFeatures(rec, repoPath)
+	if err := initSubmodules(ctx, config, rec, repoPath); err != nil {
+		return err
+	}
+	return fetchLFSObjects(ctx, config, rec, repoPath)
+}
+
+// initSubmodules recursively initializes and checks out the submodules of a fresh or updated clone
+func initSubmodules(ctx context.Context, config Config, rec *RepoRecord, repoPath string) error {
+	if !config.Submodules || !rec.HasSubmodules || config.Mirror {
+		return nil
+	}
+
+	fmt.Printf("  Initializing submodules\n")
+	args := submoduleURLRewrites(rec.CloneURL(config.UseSSH), config.UseSSH)
+	args = append(args, "submodule", "update", "--init", "--recursive", "--progress")
+	if config.Depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", config.Depth))
+	}
+
+	if err := runGit(ctx, repoPath, lfsEnv(config), args...); err != nil {
+		return fmt.Errorf("git submodule update failed: %w", err)
+	}
+	return nil
+}
+
+// lfsEnv returns the environment for git commands that check out files, skipping LFS downloads with -lfs=skip
+func lfsEnv(config Config) []string {
+	if config.LFS == "skip" {
+		return []string{"GIT_LFS_SKIP_SMUDGE=1"}
+	}
+	return nil
+}
+
+// fetchLFSObjects downloads the LFS objects of a repository with -lfs=fetch: the checked-out files
+// for working trees, or the objects of all refs for mirrors
+func fetchLFSObjects(ctx context.Context, config Config, rec *RepoRecord, repoPath string) error {
+	if config.LFS != "fetch" || !rec.UsesLFS {
+		return nil
+	}
+
+	args := []string{"lfs", "pull"}
+	if config.Mirror {
+		args = []string{"lfs", "fetch", "--all"}
+	}
+
+	fmt.Printf("  Fetching Git LFS objects\n")
+	if err := runGit(ctx, repoPath, nil, args...); err != nil {
+		return fmt.Errorf("git lfs failed (is git-lfs installed?): %w", err)
+	}
+	return nil
+}
+
+// displayRepoFeatures lists the repositories that use submodules or Git LFS
+func displayRepoFeatures(records []*RepoRecord) {
+	var submodules, lfs []string
+	for _, rec := range records {
+		if rec.HasSubmodules {
+			submodules = append(submodules, rec.Name)
+		}
+		if rec.UsesLFS {
+			lfs = append(lfs, rec.Name)
+		}
+	}
+
+	if len(submodules) > 0 {
+		fmt.Printf("\n📦 Repositories using submodules (%d):\n", len(submodules))
+		for _, name := range submodules {
+			fmt.Printf("   - %s\n", name)
+		}
+	}
+	if len(lfs) > 0 {
+		fmt.Printf("\n🗄️  Repositories using Git LFS (%d):\n", len(lfs))
+		for _, name := range lfs {
+			fmt.Printf("   - %s\n", name)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runSync implements the sync command: list and filter repositories, then clone new ones and
+// update existing mirrors and shallow clones. It is also run when no command is given.
+func runSync(args []string) int {
+	var config Config
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	registerSourceFlags(fs, &config)
+	fs.BoolVar(&config.UseSSH, "ssh", false, "Use SSH URLs instead of HTTPS")
+	fs.StringVar(&config.Backend, "backend", backendExec, "Clone backend: exec (git binary) or go-git (no git binary needed)")
+	fs.StringVar(&config.SSHKey, "ssh-key", "", "Private key for SSH authentication with -backend=go-git (default: SSH agent)")
+	fs.BoolVar(&config.NoProgress, "no-progress", false, "Print plain progress lines instead of the live progress view on terminals")
+	fs.BoolVar(&config.DryRun, "dry-run", false, "List, filter and check catalogs, then print what would be cloned, updated or skipped without touching the target directory")
+	fs.BoolVar(&config.Mirror, "mirror", false, "Create bare mirror clones (all branches, tags and notes) stored as name.git, updating them on later runs")
+	fs.IntVar(&config.Depth, "depth", 0, "Create shallow clones with history truncated to this many commits; existing shallow clones are updated to this depth")
+	fs.BoolVar(&config.Unshallow, "unshallow", false, "Convert existing shallow clones into full clones (git fetch --unshallow)")
+	fs.StringVar(&config.Filter, "filter", "", "Partial clone filter: blob:none (fetch file contents on demand) or tree:0 (also trees)")
+	fs.BoolVar(&config.SingleBranch, "single-branch", false, "Only clone the default branch")
+	fs.DurationVar(&config.CloneTimeout, "clone-timeout", 0, "Maximum duration of a single clone or update, e.g. 10m (default: no limit)")
+	fs.BoolVar(&config.Submodules, "submodules", false, "Recursively initialize submodules, rewriting their URLs to match -ssh")
+	fs.StringVar(&config.LFS, "lfs", "", "Git LFS objects: fetch (download them) or skip (GIT_LFS_SKIP_SMUDGE); default: git's behavior")
+	fs.StringVar(&config.SparsePatterns, "sparse", "", "Comma-separated sparse-checkout patterns (gitignore syntax) to check out, e.g. /.catalog.yml,/go.mod,/.github/")
+	fs.StringVar(&config.SparseConfigPath, "sparse-config", "", "YAML file with default and per catalog type sparse-checkout patterns")
+	fs.BoolVar(&config.RetryFailed, "retry-failed", false, "Only clone the repositories that failed in the last run again (from -failures-file), without listing or checking catalogs")
+	fs.StringVar(&config.FailuresPath, "failures-file", "", "Write the repositories that failed to clone or update to this JSON file (default: "+failuresFileName+" in -dir)")
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: git-repo-downloader [sync] [flags]\n\n")
+		fmt.Fprintf(w, "Downloads all repositories from GitHub organizations or GitLab groups: new repositories are\n")
+		fmt.Fprintf(w, "cloned, existing mirrors and shallow clones are updated.\n\n")
+		fs.PrintDefaults()
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Examples:")
+		fmt.Fprintln(w, "  # Download public repositories from GitHub")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=github -org=kubernetes")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Download all repositories with authentication")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=github -org=mycompany -token=ghp_xxxx")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Download from GitLab group")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=gitlab -org=mygroup -token=glpat_xxxx")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Download to specific directory using SSH")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=github -org=myorg -token=ghp_xxxx -dir=~/repos -ssh")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Download from self-hosted GitLab")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=gitlab -org=mygroup -token=glpat_xxxx -gitlab-url=https://gitlab.company.com")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Download only production repositories (with component.lifecycle: production)")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=github -org=myorg -token=ghp_xxxx --prod")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Download from ALL GitLab groups (auto-discover)")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=gitlab -token=glpat_xxxx -gitlab-url=https://gitlab.company.com --all-groups")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Back up all refs as bare mirrors (re-run to update them)")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=github -org=myorg -token=ghp_xxxx -dir=/backup -mirror")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Only fetch the tip of the default branch for code analysis")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=github -org=myorg -token=ghp_xxxx -depth=1 -single-branch -filter=blob:none")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Org-wide scan checking out only a few files per repository")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=gitlab -org=mygroup -token=glpat_xxxx -sparse=/.catalog.yml,/go.mod,/Dockerfile,/.github/")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Preview what a production-only run would clone, update or skip")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=github -org=myorg -token=ghp_xxxx --prod -skip-archived -dry-run")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # After a run with failures, clone only the failed repositories again")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=gitlab -token=glpat_xxxx --all-groups -retry-failed")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "  # Write a CSV inventory of every discovered repository")
+		fmt.Fprintln(w, "  git-repo-downloader sync -platform=github -org=myorg -token=ghp_xxxx -inventory=inventory.csv")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Exit codes:")
+		fmt.Fprintln(w, "  0  all repositories were cloned, updated or already present")
+		fmt.Fprintln(w, "  2  invalid flags or configuration")
+		fmt.Fprintln(w, "  3  some repositories failed (see the failure table and -failures-file)")
+		fmt.Fprintln(w, "  4  listing failed or all repositories failed")
+		fmt.Fprintln(w, "  5  interrupted or -total-timeout reached")
+	}
+	fs.Parse(args)
+
+	if err := validateSourceConfig(&config); err != nil {
+		fs.Usage()
+		fmt.Fprintf(os.Stderr, "\nError: %v\n", err)
+		return exitError
+	}
+
+	// Load sparse-checkout patterns and validate clone options
+	sparse, err := loadSparseConfig(config.SparseConfigPath, config.SparsePatterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	config.Sparse = sparse
+	if err := validateCloneOptions(config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+	if config.SSHKey, err = expandHomeDir(config.SSHKey); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: error getting home directory: %v\n", err)
+		return exitError
+	}
+	if config.FailuresPath, err = expandHomeDir(config.FailuresPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: error getting home directory: %v\n", err)
+		return exitError
+	}
+	if config.RetryFailed && config.DryRun {
+		fmt.Fprintf(os.Stderr, "Error: -retry-failed cannot be used with -dry-run\n")
+		return exitError
+	}
+
+	config.Events = newEventWriter(config.Output)
+	if !config.DryRun {
+		config.Progress = newProgressDisplay(config)
+	}
+
+	// Create target directory if it doesn't exist, a dry run leaves the file system alone
+	if !config.DryRun {
+		if err := os.MkdirAll(config.TargetDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to create target directory '%s': %v\n", config.TargetDir, err)
+			return exitError
+		}
+	}
+
+	// Print configuration
+	fmt.Printf("Git Repository Downloader\n")
+	fmt.Printf("=========================\n")
+	fmt.Printf("Platform: %s\n", config.Platform)
+	if config.AllGroups {
+		fmt.Printf("Mode: Auto-discover all groups\n")
+	} else {
+		fmt.Printf("Organization/Group: %s\n", config.Organization)
+	}
+	fmt.Printf("Target directory: %s\n", config.TargetDir)
+	if config.Token != "" {
+		fmt.Printf("Authentication: Using provided token\n")
+	} else {
+		fmt.Printf("Authentication: No token (public repositories only)\n")
+	}
+	fmt.Printf("Clone method: %s\n", getCloneMethod(config.UseSSH))
+	fmt.Printf("Clone backend: %s\n", config.Backend)
+	if config.Mirror {
+		fmt.Printf("Clone mode: Mirror (bare repositories with all refs)\n")
+	}
+	if config.Depth > 0 {
+		fmt.Printf("Clone depth: %d\n", config.Depth)
+	}
+	if config.Unshallow {
+		fmt.Printf("Shallow clones: Converted into full clones\n")
+	}
+	if config.SingleBranch {
+		fmt.Printf("Branches: Default branch only\n")
+	}
+	if config.Filter != "" {
+		fmt.Printf("Partial clone filter: %s\n", config.Filter)
+	}
+	if config.APIBudget > 0 {
+		fmt.Printf("API budget: %d requests\n", config.APIBudget)
+	}
+	if config.CloneTimeout > 0 {
+		fmt.Printf("Clone timeout: %s\n", config.CloneTimeout)
+	}
+	if config.TotalTimeout > 0 {
+		fmt.Printf("Total timeout: %s\n", config.TotalTimeout)
+	}
+	if config.Submodules {
+		fmt.Printf("Submodules: Recursively initialized\n")
+	}
+	if config.LFS != "" {
+		fmt.Printf("Git LFS: %s\n", config.LFS)
+	}
+	if config.Sparse.enabled() {
+		fmt.Printf("Sparse checkout: Enabled (%d default patterns, %d catalog types)\n", len(config.Sparse.Default), len(config.Sparse.Types))
+	}
+	if config.Platform == "gitlab" {
+		fmt.Printf("GitLab URL: %s\n", config.GitLabURL)
+	}
+	if config.ProdMode {
+		fmt.Printf("Production mode: Enabled (only downloading repos with lifecycle: production)\n")
+	}
+	if config.SkipArchived {
+		fmt.Printf("Archived repositories: Skipped\n")
+	}
+	if config.DryRun {
+		fmt.Printf("Dry run: Nothing will be cloned or updated\n")
+	}
+	if config.RetryFailed {
+		fmt.Printf("Retry failed: Only repositories that failed in the last run\n")
+	}
+	fmt.Println()
+
+	// Remove leftovers of interrupted clones so they are cloned again
+	if !config.DryRun {
+		removed, err := repairPartialClones(config.TargetDir)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		if len(removed) > 0 {
+			fmt.Printf("🧹 Removed %d partial clones left by an interrupted run, they will be cloned again:\n", len(removed))
+			for _, name := range removed {
+				fmt.Printf("   - %s\n", name)
+			}
+			fmt.Println()
+		}
+	}
+
+	if err := setupAPI(&config); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return exitError
+	}
+
+	// Ctrl-C and -total-timeout cancel the run: git is stopped and the summary is still printed
+	ctx, cancel := newRunContext(config)
+	defer cancel()
+
+	// Download repositories based on platform, or only those that failed in the last run
+	var records []*RepoRecord
+	if config.RetryFailed {
+		records, err = retryFailedRepos(ctx, config)
+	} else {
+		records, err = discoverRepos(ctx, config)
+	}
+	interrupted := ctx.Err() != nil
+
+	// With -all-groups, groups that couldn't be listed fail the run, but the other groups are synced
+	var groupErr *groupListingError
+	if errors.As(err, &groupErr) {
+		err = nil
+	}
+	listingFailures := 0
+	if groupErr != nil {
+		listingFailures = len(groupErr.Groups)
+	}
+	if err != nil {
+		if interrupted {
+			fmt.Fprintf(os.Stderr, "Error: Failed to download repositories: %s\n", cancelReason(ctx))
+			return exitInterrupted
+		}
+		fmt.Fprintf(os.Stderr, "Error: Failed to download repositories: %v\n", err)
+		return exitTotalFailure
+	}
+
+	if interrupted {
+		markNotStarted(records, cancelReason(ctx))
+		displayInterruptedSummary(records, cancelReason(ctx))
+	} else if config.DryRun {
+		displayPlan(records)
+	} else {
+		switch syncExitCode(records, listingFailures) {
+		case exitOK:
+			fmt.Printf("\n✅ Repository download completed successfully!\n")
+			fmt.Printf("All repositories have been downloaded to: %s\n", config.TargetDir)
+		case exitPartialFailure:
+			fmt.Printf("\n⚠️  Repository download completed with %d failures\n", countOutcome(records, outcomeFailed)+listingFailures)
+			fmt.Printf("The other repositories have been downloaded to: %s\n", config.TargetDir)
+		default:
+			fmt.Printf("\n❌ Repository download failed: none of the %d repositories could be cloned\n", countOutcome(records, outcomeFailed))
+		}
+	}
+	displayFailures(records)
+	displayGroupFailures(groupErr)
+	displayBrokenClones(records)
+	if !config.DryRun {
+		if written, err := writeFailures(config, records, interrupted); err != nil {
+			log.Printf("Warning: %v", err)
+		} else if written > 0 {
+			fmt.Printf("📄 Failed repositories written to: %s\n", failuresPath(config))
+		}
+		if err := updateSyncState(config, records); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	config.Rate.displaySummary()
+	config.Cache.displaySummary()
+
+	displayRepoFeatures(records)
+	writeRunInventory(config, records)
+	config.Events.summary(config, records, interrupted)
+
+	if interrupted {
+		return exitInterrupted
+	}
+
+	// If production mode is enabled, show final scan results
+	if config.ProdMode && !config.DryRun {
+		fmt.Printf("\n🔍 Final scan of downloaded repositories...\n")
+		catalogInfo, err := scanForCatalogFiles(config.TargetDir)
+		if err != nil {
+			log.Printf("Warning: Failed to scan for catalog files: %v", err)
+		} else {
+			displayCatalogResults(catalogInfo)
+		}
+	}
+	return syncExitCode(records, listingFailures)
+}